@@ -3,6 +3,7 @@ package ast
 import (
     "bytes"
     "strings"
+    "math/big"
     "monkey_interpreter/token"
 )
 
@@ -12,7 +13,7 @@ type Node interface {
     String() string
 }
 
-// let, return, 式文の３種のみ
+// let, return, 式文, ループ関連の文
 type Statement interface {
     Node
     statementNode()
@@ -23,6 +24,13 @@ type Expression interface {
     expressionNode()
 }
 
+// 値を分解して名前に束縛する際の左辺. let, constの左辺と関数の引数, matchの各armに書ける
+// Identifier, ArrayPattern, HashPattern, RestPattern, DefaultPattern, LiteralPatternのいずれか
+type Pattern interface {
+    Expression
+    patternNode()
+}
+
 // ASTのroot node
 // すべてのmonkeyプログラムは文の集まり
 type Program struct {
@@ -49,8 +57,11 @@ func (p *Program) String() string {
 type LetStatement struct {
     // let <identifier> = <expression>;
     // ex. let a = 5 * 5;
-    Token token.Token // `let` token
+    // let <pattern> = <expression>;
+    // ex. let [a, b] = arr;
+    Token token.Token // `let` or `const` token
     Name *Identifier
+    Pattern Pattern // 分割代入の場合のみ. このときNameはnil
     Value Expression
 }
 
@@ -64,7 +75,11 @@ func (ls *LetStatement) String() string {
     var out bytes.Buffer
 
     out.WriteString(ls.TokenLiteral() + " ")
-    out.WriteString(ls.Name.String())
+    if ls.Pattern != nil {
+        out.WriteString(ls.Pattern.String())
+    } else {
+        out.WriteString(ls.Name.String())
+    }
     out.WriteString(" = ")
 
     if ls.Value != nil {
@@ -96,6 +111,20 @@ func (rs *ReturnStatement) String() string {
     return out.String()
 }
 
+type ThrowStatement struct {
+    // throw <expression>;
+    Token token.Token // `throw` token
+    Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+func (ts *ThrowStatement) TokenLiteral() string {
+    return ts.Token.Literal
+}
+func (ts *ThrowStatement) String() string {
+    return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
 type ExpressionStatement struct {
     // <expression>;
     Token token.Token
@@ -133,6 +162,80 @@ func (bs *BlockStatement) String() string {
     return out.String()
 }
 
+type WhileStatement struct {
+    // while (<condition>) { <body> }
+    Token token.Token
+    Cond Expression
+    Body *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) TokenLiteral() string {
+    return ws.Token.Literal
+}
+func (ws *WhileStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(ws.TokenLiteral())
+    out.WriteString(ws.Cond.String())
+    out.WriteString(" ")
+    out.WriteString(ws.Body.String())
+
+    return out.String()
+}
+
+type ForStatement struct {
+    // for (<identifier> in <expression>) { <body> }
+    Token token.Token
+    Var *Identifier
+    Iterable Expression
+    Body *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {}
+func (fs *ForStatement) TokenLiteral() string {
+    return fs.Token.Literal
+}
+func (fs *ForStatement) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(fs.TokenLiteral())
+    out.WriteString("(")
+    out.WriteString(fs.Var.String())
+    out.WriteString(" in ")
+    out.WriteString(fs.Iterable.String())
+    out.WriteString(") ")
+    out.WriteString(fs.Body.String())
+
+    return out.String()
+}
+
+type BreakStatement struct {
+    // break;
+    Token token.Token
+}
+
+func (bs *BreakStatement) statementNode() {}
+func (bs *BreakStatement) TokenLiteral() string {
+    return bs.Token.Literal
+}
+func (bs *BreakStatement) String() string {
+    return bs.TokenLiteral() + ";"
+}
+
+type ContinueStatement struct {
+    // continue;
+    Token token.Token
+}
+
+func (cs *ContinueStatement) statementNode() {}
+func (cs *ContinueStatement) TokenLiteral() string {
+    return cs.Token.Literal
+}
+func (cs *ContinueStatement) String() string {
+    return cs.TokenLiteral() + ";"
+}
+
 // identifierは値を生成するため式(expression)
 type Identifier struct {
     Token token.Token
@@ -140,6 +243,7 @@ type Identifier struct {
 }
 
 func (id *Identifier) expressionNode() {}
+func (id *Identifier) patternNode() {}
 func (id *Identifier) TokenLiteral() string {
     return id.Token.Literal
 }
@@ -147,9 +251,112 @@ func (id *Identifier) String() string {
     return id.Value
 }
 
+type ArrayPattern struct {
+    // [<pattern>, <pattern>, ...<identifier>]
+    // 最後の要素に限りRestPatternを置ける
+    Token token.Token
+    Elems []Pattern
+}
+
+func (ap *ArrayPattern) expressionNode() {}
+func (ap *ArrayPattern) patternNode() {}
+func (ap *ArrayPattern) TokenLiteral() string {
+    return ap.Token.Literal
+}
+func (ap *ArrayPattern) String() string {
+    elems := []string{}
+    for _, elem := range ap.Elems {
+        elems = append(elems, elem.String())
+    }
+    return "[" + strings.Join(elems, ", ") + "]"
+}
+
+type HashPatternEntry struct {
+    // <identifier> または <identifier>: <pattern>
+    Key *Identifier
+    Value Pattern // 省略形の場合はKeyと同じ
+}
+
+type HashPattern struct {
+    // {<identifier>, <identifier>: <pattern>, ...<identifier>}
+    Token token.Token
+    Entries []*HashPatternEntry
+    Rest *RestPattern
+}
+
+func (hp *HashPattern) expressionNode() {}
+func (hp *HashPattern) patternNode() {}
+func (hp *HashPattern) TokenLiteral() string {
+    return hp.Token.Literal
+}
+func (hp *HashPattern) String() string {
+    entries := []string{}
+    for _, entry := range hp.Entries {
+        if entry.Value == Pattern(entry.Key) {
+            entries = append(entries, entry.Key.String())
+        } else {
+            entries = append(entries, entry.Key.String() + ": " + entry.Value.String())
+        }
+    }
+    if hp.Rest != nil {
+        entries = append(entries, hp.Rest.String())
+    }
+    return "{" + strings.Join(entries, ", ") + "}"
+}
+
+type RestPattern struct {
+    // ...<identifier>
+    // 残りの要素をまとめて束縛する
+    Token token.Token // `...` token
+    Name *Identifier
+}
+
+func (rp *RestPattern) expressionNode() {}
+func (rp *RestPattern) patternNode() {}
+func (rp *RestPattern) TokenLiteral() string {
+    return rp.Token.Literal
+}
+func (rp *RestPattern) String() string {
+    return rp.TokenLiteral() + rp.Name.String()
+}
+
+type DefaultPattern struct {
+    // <pattern> = <expression>
+    // 関数の引数にのみ書け、引数が渡されなかった時に呼び出し時点で評価される
+    Token token.Token // `=` token
+    Target Pattern
+    Default Expression
+}
+
+func (dp *DefaultPattern) expressionNode() {}
+func (dp *DefaultPattern) patternNode() {}
+func (dp *DefaultPattern) TokenLiteral() string {
+    return dp.Token.Literal
+}
+func (dp *DefaultPattern) String() string {
+    return dp.Target.String() + " = " + dp.Default.String()
+}
+
+type LiteralPattern struct {
+    // 整数, 文字列, 真偽値のリテラル. matchのarmにのみ書け、値が等しい時にだけマッチする
+    Token token.Token
+    Value Expression
+}
+
+func (lp *LiteralPattern) expressionNode() {}
+func (lp *LiteralPattern) patternNode() {}
+func (lp *LiteralPattern) TokenLiteral() string {
+    return lp.Token.Literal
+}
+func (lp *LiteralPattern) String() string {
+    return lp.Value.String()
+}
+
 type IntegerLiteral struct {
     Token token.Token
     Value int64
+    // int64に収まらない値のみBigに入れ、Valueは使わない
+    Big *big.Int
 }
 
 func (il *IntegerLiteral) expressionNode() {}
@@ -232,6 +439,47 @@ func (ie *InfixExpression) String() string {
     return out.String()
 }
 
+type AssignExpression struct {
+    // <identifier> <assign operator> <expression>
+    // <expression>[<expression>] <assign operator> <expression>
+    Token token.Token // `=` or `+=`, `-=`, ...
+    Target Expression // Identifier or IndexExpression
+    Operator string
+    Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+func (ae *AssignExpression) TokenLiteral() string {
+    return ae.Token.Literal
+}
+func (ae *AssignExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("(")
+    out.WriteString(ae.Target.String() + " ")
+    out.WriteString(ae.Operator)
+    out.WriteString(" " + ae.Value.String())
+    out.WriteString(")")
+
+    return out.String()
+}
+
+type TernaryExpression struct {
+    // <condition> ? <consequence> : <alternative>
+    Token token.Token // `?` token
+    Cond Expression
+    Cons Expression
+    Alt Expression
+}
+
+func (te *TernaryExpression) expressionNode() {}
+func (te *TernaryExpression) TokenLiteral() string {
+    return te.Token.Literal
+}
+func (te *TernaryExpression) String() string {
+    return "(" + te.Cond.String() + " ? " + te.Cons.String() + " : " + te.Alt.String() + ")"
+}
+
 type IfExpression struct {
     // if (<condition>) { <consequence> } else { <alternative> }
     Token token.Token
@@ -259,11 +507,85 @@ func (ie *IfExpression) String() string {
     return out.String()
 }
 
+type TryExpression struct {
+    // try { <body> } catch (<pattern>) { <catch> } finally { <finally> }
+    // catchとfinallyはどちらか一方を省略できる
+    Token token.Token
+    Body *BlockStatement
+    CatchParam Pattern
+    Catch *BlockStatement
+    Finally *BlockStatement
+}
+
+func (te *TryExpression) expressionNode() {}
+func (te *TryExpression) TokenLiteral() string {
+    return te.Token.Literal
+}
+func (te *TryExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(te.TokenLiteral())
+    out.WriteString(te.Body.String())
+    if te.Catch != nil {
+        out.WriteString("catch(" + te.CatchParam.String() + ")")
+        out.WriteString(te.Catch.String())
+    }
+    if te.Finally != nil {
+        out.WriteString("finally")
+        out.WriteString(te.Finally.String())
+    }
+
+    return out.String()
+}
+
+type MatchArm struct {
+    // <pattern> => <expression> または <pattern> if <guard> => <expression>
+    Token token.Token // patternの先頭のtoken
+    Pattern Pattern
+    Guard Expression
+    Body Expression
+}
+
+func (ma *MatchArm) String() string {
+    var out bytes.Buffer
+
+    out.WriteString(ma.Pattern.String())
+    if ma.Guard != nil {
+        out.WriteString(" if ")
+        out.WriteString(ma.Guard.String())
+    }
+    out.WriteString(" => ")
+    out.WriteString(ma.Body.String())
+
+    return out.String()
+}
+
+type MatchExpression struct {
+    // match (<subject>) { <arm>, <arm>, ... }
+    // armを上から順に試し、最初にマッチしたarmのbodyの値を返す
+    Token token.Token
+    Subject Expression
+    Arms []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string {
+    return me.Token.Literal
+}
+func (me *MatchExpression) String() string {
+    arms := []string{}
+    for _, arm := range me.Arms {
+        arms = append(arms, arm.String())
+    }
+
+    return me.TokenLiteral() + " (" + me.Subject.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
 type FunctionLiteral struct {
     // fn <parameters> <block statement>
-    // 関数リテラルは関数定義に用いられるため、parametersには*Identifier型しか来ない
+    // 関数リテラルは関数定義に用いられるため、parametersには束縛先となるPatternしか来ない
     Token token.Token
-    Params []*Identifier
+    Params []Pattern
     Body *BlockStatement
 }
 
@@ -274,12 +596,15 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 func (fl *FunctionLiteral) String() string {
     var out bytes.Buffer
 
-    out.WriteString(fl.TokenLiteral())
-    out.WriteString("(")
+    params := []string{}
     for _, param := range fl.Params {
-        out.WriteString(param.String())
+        params = append(params, param.String())
     }
 
+    out.WriteString(fl.TokenLiteral())
+    out.WriteString("(")
+    out.WriteString(strings.Join(params, ", "))
+
     out.WriteString(")")
     out.WriteString(fl.Body.String())
     return out.String()
@@ -313,6 +638,22 @@ func (fc *FunctionCall) String() string {
     return out.String()
 }
 
+type NamedArgument struct {
+    // <identifier>: <expression>
+    // 関数呼び出しの引数にのみ書ける
+    Token token.Token
+    Name *Identifier
+    Value Expression
+}
+
+func (na *NamedArgument) expressionNode() {}
+func (na *NamedArgument) TokenLiteral() string {
+    return na.Token.Literal
+}
+func (na *NamedArgument) String() string {
+    return na.Name.String() + ": " + na.Value.String()
+}
+
 type ArrayLiteral struct {
     Token token.Token
     Elems []Expression
@@ -338,10 +679,12 @@ func (al *ArrayLiteral) String() string {
 }
 
 type IndexExpression struct {
-    // <expression>[<expression>]
+    // <expression>[<expression>] または <expression>?.[<expression>]
     Token token.Token
     Left Expression
     Index Expression
+    // ?.[ の場合はtrue. Leftがnullであれば、後に続く添字や呼び出しも含めて評価せずnullとなる
+    Optional bool
 }
 
 func (ie *IndexExpression) expressionNode() {}
@@ -353,6 +696,9 @@ func (ie *IndexExpression) String() string {
 
     out.WriteString("(")
     out.WriteString(ie.Left.String())
+    if ie.Optional {
+        out.WriteString("?.")
+    }
     out.WriteString("[")
     out.WriteString(ie.Index.String())
     out.WriteString("]")
@@ -361,10 +707,117 @@ func (ie *IndexExpression) String() string {
     return out .String()
 }
 
+type SliceExpression struct {
+    // <expression>[<start>:<end>:<step>]
+    // start, end, stepはいずれも省略でき、省略した場合はnil
+    Token token.Token // `[` token
+    Left Expression
+    Start Expression
+    End Expression
+    Step Expression
+    // ?.[ の場合はtrue. Leftがnullであれば、後に続く添字や呼び出しも含めて評価せずnullとなる
+    Optional bool
+}
+
+func (se *SliceExpression) expressionNode() {}
+func (se *SliceExpression) TokenLiteral() string {
+    return se.Token.Literal
+}
+func (se *SliceExpression) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("(")
+    out.WriteString(se.Left.String())
+    if se.Optional {
+        out.WriteString("?.")
+    }
+    out.WriteString("[")
+    if se.Start != nil {
+        out.WriteString(se.Start.String())
+    }
+    out.WriteString(":")
+    if se.End != nil {
+        out.WriteString(se.End.String())
+    }
+    if se.Step != nil {
+        out.WriteString(":")
+        out.WriteString(se.Step.String())
+    }
+    out.WriteString("])")
+
+    return out.String()
+}
+
+type ComprehensionClause struct {
+    // for <pattern> in <iterable> if <condition>
+    // for <pattern>, <pattern> in <iterable> if <condition>
+    // 2つ目の形ではハッシュのキーと値、それ以外では添字と要素を受け取る. ifは省略できる
+    Token token.Token // `for` token
+    Key Pattern // 1つ目の形の場合はnil
+    Value Pattern
+    Iterable Expression
+    Cond Expression
+}
+
+func (cc *ComprehensionClause) String() string {
+    var out bytes.Buffer
+
+    out.WriteString("for ")
+    if cc.Key != nil {
+        out.WriteString(cc.Key.String() + ", ")
+    }
+    out.WriteString(cc.Value.String())
+    out.WriteString(" in ")
+    out.WriteString(cc.Iterable.String())
+    if cc.Cond != nil {
+        out.WriteString(" if ")
+        out.WriteString(cc.Cond.String())
+    }
+
+    return out.String()
+}
+
+type ArrayComprehension struct {
+    // [<expression> <clause>]
+    Token token.Token
+    Elem Expression
+    Clause *ComprehensionClause
+}
+
+func (ac *ArrayComprehension) expressionNode() {}
+func (ac *ArrayComprehension) TokenLiteral() string {
+    return ac.Token.Literal
+}
+func (ac *ArrayComprehension) String() string {
+    return "[" + ac.Elem.String() + " " + ac.Clause.String() + "]"
+}
+
+type HashComprehension struct {
+    // {<expression>: <expression> <clause>}
+    Token token.Token
+    Key Expression
+    Value Expression
+    Clause *ComprehensionClause
+}
+
+func (hc *HashComprehension) expressionNode() {}
+func (hc *HashComprehension) TokenLiteral() string {
+    return hc.Token.Literal
+}
+func (hc *HashComprehension) String() string {
+    return "{" + hc.Key.String() + ":" + hc.Value.String() + " " + hc.Clause.String() + "}"
+}
+
+type HashLiteralPair struct {
+    Key Expression
+    Value Expression
+}
+
 type HashLiteral struct {
     // {<expression>: <expression>, <expression>: <expression>, ...}
+    // 評価結果のハッシュが書いた順を保つよう、ペアはソース上の順に並べる
     Token token.Token
-    Pairs map[Expression]Expression
+    Pairs []HashLiteralPair
 }
 
 func (hl *HashLiteral) expressionNode() {}
@@ -377,8 +830,8 @@ func (hl *HashLiteral) String() string {
     out.WriteString("{")
 
     pairs := []string{}
-    for key, val := range hl.Pairs {
-        pairs = append(pairs, key.String() + ":" + val.String())
+    for _, pair := range hl.Pairs {
+        pairs = append(pairs, pair.Key.String() + ":" + pair.Value.String())
     }
     out.WriteString(strings.Join(pairs, ", "))
 
@@ -386,3 +839,29 @@ func (hl *HashLiteral) String() string {
 
     return out.String()
 }
+
+type SetLiteral struct {
+    // {<expression>, <expression>, ...}
+    // 空の集合は空のハッシュと区別できないので、リテラルは1つ以上の要素を持つ
+    Token token.Token
+    Elems []Expression
+}
+
+func (sl *SetLiteral) expressionNode() {}
+func (sl *SetLiteral) TokenLiteral() string {
+    return sl.Token.Literal
+}
+func (sl *SetLiteral) String() string {
+    var out bytes.Buffer
+
+    elems := []string{}
+    for _, e := range sl.Elems {
+        elems = append(elems, e.String())
+    }
+
+    out.WriteString("{")
+    out.WriteString(strings.Join(elems, ", "))
+    out.WriteString("}")
+
+    return out.String()
+}
@@ -2,23 +2,47 @@ package eval
 
 import (
     "fmt"
+    "math"
+    "sort"
+    "unicode/utf8"
     "monkey_interpreter/object"
 )
 
+// builtin関数の名前を辞書順で返す
+func BuiltinNames() []string {
+    names := []string{}
+    for name := range builtins {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
 var builtins = map[string]*object.Builtin {
     "len": &object.Builtin {
         Fn: func(args ...object.Object) object.Object {
             l := len(args)
             if l != 1 {
-                return newError("wrong number of arguments. got=%d, want=1", l)
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
             }
             switch arg := args[0].(type) {
             case *object.String:
-                return &object.Integer{Value: int64(len(arg.Value))}
+                // バイト数ではなく文字数を返す
+                return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
             case *object.Array:
                 return &object.Integer{Value: int64(len(arg.Elems))}
+            case *object.Range:
+                n := arg.Len()
+                if n > math.MaxInt64 {
+                    return newErrorKind(valueError, "range length overflows int64: %s", arg.Inspect())
+                }
+                return &object.Integer{Value: int64(n)}
+            case *object.Hash:
+                return &object.Integer{Value: int64(arg.Len())}
+            case *object.Set:
+                return &object.Integer{Value: int64(arg.Len())}
             default:
-                return newError("argument to `len` not supported, got %s", arg.Type())
+                return newErrorKind(typeError, "argument to `len` not supported, got %s", arg.Type())
             }
         },
     },
@@ -26,7 +50,7 @@ var builtins = map[string]*object.Builtin {
         Fn: func(args ...object.Object) object.Object {
             l := len(args)
             if l != 1 {
-                return newError("wrong number of arguments. got=%d, want=1", l)
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
             }
 
             switch arg := args[0].(type) {
@@ -37,7 +61,7 @@ var builtins = map[string]*object.Builtin {
                     return arg.Elems[0]
                 }
             default:
-                return newError("argument to `first` not supported, got %s", arg.Type())
+                return newErrorKind(typeError, "argument to `first` not supported, got %s", arg.Type())
             }
         },
     },
@@ -45,7 +69,7 @@ var builtins = map[string]*object.Builtin {
         Fn: func(args ...object.Object) object.Object {
             l := len(args)
             if l != 1 {
-                return newError("wrong number of arguments. got=%d, want=1", l)
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
             }
 
             switch arg := args[0].(type) {
@@ -56,7 +80,7 @@ var builtins = map[string]*object.Builtin {
                     return arg.Elems[len(arg.Elems) - 1]
                 }
             default:
-                return newError("argument to `last` not supported, got %s", arg.Type())
+                return newErrorKind(typeError, "argument to `last` not supported, got %s", arg.Type())
             }
         },
     },
@@ -64,7 +88,7 @@ var builtins = map[string]*object.Builtin {
         Fn: func(args ...object.Object) object.Object {
             l := len(args)
             if l != 1 {
-                return newError("wrong number of arguments. got=%d, want=1", l)
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
             }
 
             switch arg := args[0].(type) {
@@ -72,10 +96,12 @@ var builtins = map[string]*object.Builtin {
                 if len(arg.Elems) == 0 {
                     return NULL
                 } else {
-                    return &object.Array{Elems: arg.Elems[1:]}
+                    newArr := make([]object.Object, len(arg.Elems) - 1)
+                    copy(newArr, arg.Elems[1:])
+                    return &object.Array{Elems: newArr}
                 }
             default:
-                return newError("argument to `rest` not supported, got %s", arg.Type())
+                return newErrorKind(typeError, "argument to `rest` not supported, got %s", arg.Type())
             }
         },
     },
@@ -83,20 +109,87 @@ var builtins = map[string]*object.Builtin {
         Fn: func(args ...object.Object) object.Object {
             l := len(args)
             if l != 2 {
-                return newError("wrong number of arguments. got=%d, want=2", l)
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=2", l)
             }
 
             a, ok := args[0].(*object.Array)
             if !ok {
-                return newError("1st arg of push() needs to ARRAY_OBJ, but got %s", args[0].Type())
+                return newErrorKind(typeError, "1st arg of push() needs to ARRAY_OBJ, but got %s", args[0].Type())
             }
 
-            newArr := a.Elems
+            // 要素の代入で元の配列が書き換わらないよう、複製してから追加する
+            newArr := make([]object.Object, len(a.Elems), len(a.Elems) + 1)
+            copy(newArr, a.Elems)
 
             newArr = append(newArr, args[1])
             return &object.Array{Elems: newArr}
         },
     },
+    // 反復できる値の要素を並べた配列を作る. 範囲を具体的な配列に変換する時に用いる
+    "array": &object.Builtin {
+        Fn: func(args ...object.Object) object.Object {
+            l := len(args)
+            if l != 1 {
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
+            }
+
+            next, err := newIterator(args[0])
+            if err != nil {
+                return err
+            }
+            elems := []object.Object{}
+            for elem, ok := next(); ok; elem, ok = next() {
+                elems = append(elems, elem)
+            }
+            return &object.Array{Elems: elems}
+        },
+    },
+    // 評価を中断させない、値としてのエラーを作る. 第2引数でエラーの種類を指定できる
+    "error": &object.Builtin {
+        Fn: func(args ...object.Object) object.Object {
+            l := len(args)
+            if l != 1 && l != 2 {
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1..2", l)
+            }
+
+            msg, ok := args[0].(*object.String)
+            if !ok {
+                return newErrorKind(typeError, "argument to `error` must be STRING, got %s", args[0].Type())
+            }
+
+            err := &object.Error{Kind: genericError, Msg: msg.Value, IsValue: true}
+            if l == 2 {
+                kind, ok := args[1].(*object.String)
+                if !ok {
+                    return newErrorKind(typeError, "argument to `error` must be STRING, got %s", args[1].Type())
+                }
+                err.Kind = kind.Value
+            }
+            return err
+        },
+    },
+    "is_error": &object.Builtin {
+        Fn: func(args ...object.Object) object.Object {
+            l := len(args)
+            if l != 1 {
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
+            }
+
+            _, ok := args[0].(*object.Error)
+            return nativeBoolToBooleanObject(ok)
+        },
+    },
+    // 配列やハッシュを中身まで変更できないコピーにする. 凍結したハッシュはキーにも使える
+    "freeze": &object.Builtin {
+        Fn: func(args ...object.Object) object.Object {
+            l := len(args)
+            if l != 1 {
+                return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1", l)
+            }
+
+            return object.Freeze(args[0])
+        },
+    },
     "puts": &object.Builtin {
         Fn: func(args ...object.Object) object.Object {
             for _, arg := range args {
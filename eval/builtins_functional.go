@@ -0,0 +1,264 @@
+package eval
+
+import (
+    "sort"
+    "monkey_interpreter/object"
+)
+
+// 引数にmonkeyの関数を受け取るbuiltin関数.
+// applyFunctionからEvalを経てbuiltinsを参照するため、初期化の循環を避けてinitで登録する
+func init() {
+    builtins["map"] = &object.Builtin{Fn: builtinMap}
+    builtins["filter"] = &object.Builtin{Fn: builtinFilter}
+    builtins["reduce"] = &object.Builtin{Fn: builtinReduce}
+    builtins["sort"] = &object.Builtin{Fn: builtinSort}
+    builtins["zip"] = &object.Builtin{Fn: builtinZip}
+    builtins["range"] = &object.Builtin{Fn: builtinRange}
+}
+
+// 反復できる値の要素を全て取り出す
+func iterableElems(obj object.Object) ([]object.Object, *object.Error) {
+    if arr, ok := obj.(*object.Array); ok {
+        return arr.Elems, nil
+    }
+
+    next, err := newIterator(obj)
+    if err != nil {
+        return nil, err
+    }
+    elems := []object.Object{}
+    for elem, ok := next(); ok; elem, ok = next() {
+        elems = append(elems, elem)
+    }
+    return elems, nil
+}
+
+func isCallable(obj object.Object) bool {
+    switch obj.(type) {
+    case *object.Function, *object.Builtin:
+        return true
+    }
+    return false
+}
+
+// map(iterable, f): 各要素にfを適用した配列を返す
+func builtinMap(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=2", len(args))
+    }
+    if !isCallable(args[1]) {
+        return newErrorKind(typeError, "2nd argument to `map` must be FUNCTION, got %s", args[1].Type())
+    }
+
+    elems, err := iterableElems(args[0])
+    if err != nil {
+        return err
+    }
+
+    res := make([]object.Object, 0, len(elems))
+    for _, elem := range elems {
+        val := applyFunction(args[1], []object.Object{elem})
+        if isError(val) {
+            return val
+        }
+        res = append(res, val)
+    }
+    return &object.Array{Elems: res}
+}
+
+// filter(iterable, f): fが真を返した要素だけの配列を返す
+func builtinFilter(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=2", len(args))
+    }
+    if !isCallable(args[1]) {
+        return newErrorKind(typeError, "2nd argument to `filter` must be FUNCTION, got %s", args[1].Type())
+    }
+
+    elems, err := iterableElems(args[0])
+    if err != nil {
+        return err
+    }
+
+    res := []object.Object{}
+    for _, elem := range elems {
+        ok := applyFunction(args[1], []object.Object{elem})
+        if isError(ok) {
+            return ok
+        }
+        if isTruthly(ok) {
+            res = append(res, elem)
+        }
+    }
+    return &object.Array{Elems: res}
+}
+
+// reduce(iterable, f, initial): f(acc, elem)を順に適用して畳み込む.
+// initialを省略した場合は最初の要素から始める
+func builtinReduce(args ...object.Object) object.Object {
+    if len(args) != 2 && len(args) != 3 {
+        return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=2..3", len(args))
+    }
+    if !isCallable(args[1]) {
+        return newErrorKind(typeError, "2nd argument to `reduce` must be FUNCTION, got %s", args[1].Type())
+    }
+
+    elems, err := iterableElems(args[0])
+    if err != nil {
+        return err
+    }
+
+    var acc object.Object
+    if len(args) == 3 {
+        acc = args[2]
+    } else {
+        if len(elems) == 0 {
+            return newErrorKind(valueError, "reduce of empty sequence with no initial value")
+        }
+        acc, elems = elems[0], elems[1:]
+    }
+
+    for _, elem := range elems {
+        acc = applyFunction(args[1], []object.Object{acc, elem})
+        if isError(acc) {
+            return acc
+        }
+    }
+    return acc
+}
+
+// sort(iterable, cmp): 安定ソートした新しい配列を返す.
+// cmp(a, b)はaをbより前に置く場合に、真偽値ならtrueを、整数なら負の値を返す.
+// 省略した場合は整数同士、文字列同士を昇順に並べる
+func builtinSort(args ...object.Object) object.Object {
+    if len(args) != 1 && len(args) != 2 {
+        return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1..2", len(args))
+    }
+    if len(args) == 2 && !isCallable(args[1]) {
+        return newErrorKind(typeError, "2nd argument to `sort` must be FUNCTION, got %s", args[1].Type())
+    }
+
+    elems, err := iterableElems(args[0])
+    if err != nil {
+        return err
+    }
+    res := make([]object.Object, len(elems))
+    copy(res, elems)
+
+    // 比較中に起きた最初のエラーを覚えておき、それ以降の比較は行わない
+    var sortErr object.Object
+    less := func(a, b object.Object) bool {
+        if sortErr != nil {
+            return false
+        }
+
+        if len(args) == 1 {
+            c, err := compareObjects(a, b)
+            if err != nil {
+                sortErr = err
+                return false
+            }
+            return c < 0
+        }
+
+        switch c := applyFunction(args[1], []object.Object{a, b}).(type) {
+        case *object.Boolean:
+            return c.Value
+        case *object.Integer:
+            return c.Value < 0
+        default:
+            if isError(c) {
+                sortErr = c
+            } else {
+                sortErr = newErrorKind(typeError, "comparator must return BOOLEAN or INTEGER, got %s", c.Type())
+            }
+            return false
+        }
+    }
+
+    sort.SliceStable(res, func(i, j int) bool {
+        return less(res[i], res[j])
+    })
+    if sortErr != nil {
+        return sortErr
+    }
+    return &object.Array{Elems: res}
+}
+
+// sortの既定の順序. 整数同士と文字列同士のみ比べられる
+func compareObjects(a, b object.Object) (int, *object.Error) {
+    switch {
+    case isInteger(a) && isInteger(b):
+        return toBigInt(a).Cmp(toBigInt(b)), nil
+    case a.Type() == object.STRING_OBJ && b.Type() == object.STRING_OBJ:
+        as, bs := a.(*object.String).Value, b.(*object.String).Value
+        switch {
+        case as < bs:
+            return -1, nil
+        case as > bs:
+            return 1, nil
+        }
+        return 0, nil
+    }
+    return 0, newErrorKind(typeError, "cannot compare %s and %s", a.Type(), b.Type())
+}
+
+// zip(a, b, ...): 各引数から同じ位置の要素を集めた配列の配列を返す. 長さは最も短い引数に揃える
+func builtinZip(args ...object.Object) object.Object {
+    if len(args) == 0 {
+        return newErrorKind(argumentError, "wrong number of arguments. got=0, want=1+")
+    }
+
+    lists := make([][]object.Object, len(args))
+    n := -1
+    for i, arg := range args {
+        elems, err := iterableElems(arg)
+        if err != nil {
+            return err
+        }
+        lists[i] = elems
+        if n < 0 || len(elems) < n {
+            n = len(elems)
+        }
+    }
+
+    res := make([]object.Object, n)
+    for i := 0; i < n; i++ {
+        tuple := make([]object.Object, len(lists))
+        for j, elems := range lists {
+            tuple[j] = elems[i]
+        }
+        res[i] = &object.Array{Elems: tuple}
+    }
+    return &object.Array{Elems: res}
+}
+
+// range(stop), range(start, stop), range(start, stop, step): stopを含まない範囲を返す
+func builtinRange(args ...object.Object) object.Object {
+    if len(args) < 1 || len(args) > 3 {
+        return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=1..3", len(args))
+    }
+
+    bounds := []int64{}
+    for _, arg := range args {
+        i, ok := arg.(*object.Integer)
+        if !ok {
+            return newErrorKind(typeError, "argument to `range` must be INTEGER, got %s", arg.Type())
+        }
+        bounds = append(bounds, i.Value)
+    }
+
+    r := &object.Range{Step: 1}
+    switch len(bounds) {
+    case 1:
+        r.Stop = bounds[0]
+    case 2:
+        r.Start, r.Stop = bounds[0], bounds[1]
+    case 3:
+        r.Start, r.Stop, r.Step = bounds[0], bounds[1], bounds[2]
+    }
+    if r.Step == 0 {
+        return newErrorKind(valueError, "range step cannot be zero")
+    }
+    return r
+}
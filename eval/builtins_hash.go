@@ -0,0 +1,138 @@
+package eval
+
+import (
+    "strconv"
+    "monkey_interpreter/object"
+)
+
+// ハッシュを扱うbuiltin関数. 引数のハッシュは書き換えず、必要なら新しいハッシュを返す
+func init() {
+    builtins["keys"] = &object.Builtin{Fn: builtinKeys}
+    builtins["values"] = &object.Builtin{Fn: builtinValues}
+    builtins["entries"] = &object.Builtin{Fn: builtinEntries}
+    builtins["has"] = &object.Builtin{Fn: builtinHas}
+    builtins["delete"] = &object.Builtin{Fn: builtinDelete}
+    builtins["merge"] = &object.Builtin{Fn: builtinMerge}
+}
+
+// hと同じペアを同じ順に持つ新しいハッシュ
+func copyHash(h *object.Hash) *object.Hash {
+    res := object.NewHash()
+    for _, pair := range h.Pairs() {
+        res.Set(pair.Key.(object.Hashable), pair.Value)
+    }
+    return res
+}
+
+func hashArg(name string, args []object.Object, want int) (*object.Hash, *object.Error) {
+    if len(args) != want {
+        return nil, wrongNumberOfArgs(len(args), strconv.Itoa(want))
+    }
+    h, ok := args[0].(*object.Hash)
+    if !ok {
+        return nil, argTypeError(name, args[0], object.HASH_OBJ)
+    }
+    return h, nil
+}
+
+// keys(h): キーの配列
+func builtinKeys(args ...object.Object) object.Object {
+    h, err := hashArg("keys", args, 1)
+    if err != nil {
+        return err
+    }
+
+    elems := []object.Object{}
+    for _, pair := range h.Pairs() {
+        elems = append(elems, pair.Key)
+    }
+    return &object.Array{Elems: elems}
+}
+
+// values(h): キーと同じ順に並べた値の配列
+func builtinValues(args ...object.Object) object.Object {
+    h, err := hashArg("values", args, 1)
+    if err != nil {
+        return err
+    }
+
+    elems := []object.Object{}
+    for _, pair := range h.Pairs() {
+        elems = append(elems, pair.Value)
+    }
+    return &object.Array{Elems: elems}
+}
+
+// entries(h): [キー, 値]の配列
+func builtinEntries(args ...object.Object) object.Object {
+    h, err := hashArg("entries", args, 1)
+    if err != nil {
+        return err
+    }
+
+    elems := []object.Object{}
+    for _, pair := range h.Pairs() {
+        elems = append(elems, &object.Array{Elems: []object.Object{pair.Key, pair.Value}})
+    }
+    return &object.Array{Elems: elems}
+}
+
+// has(h, key): keyを持つかどうか. 値がnullのキーも区別できる. 集合に対しては要素を持つかどうか
+func builtinHas(args ...object.Object) object.Object {
+    if len(args) == 2 {
+        if s, ok := args[0].(*object.Set); ok {
+            elem, ok := hashable(args[1])
+            if !ok {
+                return newErrorKind(typeError, "unusable as set element: %s", args[1].Type())
+            }
+            return nativeBoolToBooleanObject(s.Has(elem))
+        }
+    }
+
+    h, err := hashArg("has", args, 2)
+    if err != nil {
+        return err
+    }
+    key, ok := hashable(args[1])
+    if !ok {
+        return newErrorKind(typeError, "unusable as hash key: %s", args[1].Type())
+    }
+
+    _, ok = h.Get(key)
+    return nativeBoolToBooleanObject(ok)
+}
+
+// delete(h, key): keyを取り除いた新しいハッシュ
+func builtinDelete(args ...object.Object) object.Object {
+    h, err := hashArg("delete", args, 2)
+    if err != nil {
+        return err
+    }
+    key, ok := hashable(args[1])
+    if !ok {
+        return newErrorKind(typeError, "unusable as hash key: %s", args[1].Type())
+    }
+
+    res := copyHash(h)
+    res.Delete(key)
+    return res
+}
+
+// merge(a, b, ...): 全てのペアを集めた新しいハッシュ. 同じキーは後の引数の値で上書きする
+func builtinMerge(args ...object.Object) object.Object {
+    if len(args) == 0 {
+        return wrongNumberOfArgs(len(args), "1+")
+    }
+
+    res := object.NewHash()
+    for _, arg := range args {
+        h, ok := arg.(*object.Hash)
+        if !ok {
+            return argTypeError("merge", arg, object.HASH_OBJ)
+        }
+        for _, pair := range h.Pairs() {
+            res.Set(pair.Key.(object.Hashable), pair.Value)
+        }
+    }
+    return res
+}
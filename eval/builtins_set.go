@@ -0,0 +1,166 @@
+package eval
+
+import (
+    "monkey_interpreter/object"
+)
+
+// 集合を扱うbuiltin関数. ハッシュと同じく引数の集合は書き換えず、新しい集合を返す
+func init() {
+    builtins["set"] = &object.Builtin{Fn: builtinSet}
+    builtins["add"] = &object.Builtin{Fn: builtinAdd}
+    builtins["remove"] = &object.Builtin{Fn: builtinRemove}
+    builtins["union"] = &object.Builtin{Fn: builtinUnion}
+    builtins["intersection"] = &object.Builtin{Fn: builtinIntersection}
+    builtins["difference"] = &object.Builtin{Fn: builtinDifference}
+}
+
+// sと同じ要素を同じ順に持つ新しい集合
+func copySet(s *object.Set) *object.Set {
+    res := object.NewSet()
+    for _, elem := range s.Elems() {
+        res.Add(elem.(object.Hashable))
+    }
+    return res
+}
+
+// 全ての引数が集合であればそれを返す
+func setArgs(name string, args []object.Object) ([]*object.Set, *object.Error) {
+    if len(args) == 0 {
+        return nil, wrongNumberOfArgs(len(args), "1+")
+    }
+    sets := make([]*object.Set, len(args))
+    for i, arg := range args {
+        s, ok := arg.(*object.Set)
+        if !ok {
+            return nil, argTypeError(name, arg, object.SET_OBJ)
+        }
+        sets[i] = s
+    }
+    return sets, nil
+}
+
+func setElem(args []object.Object) (object.Hashable, *object.Error) {
+    elem, ok := hashable(args[1])
+    if !ok {
+        return nil, newErrorKind(typeError, "unusable as set element: %s", args[1].Type())
+    }
+    return elem, nil
+}
+
+// set(), set(iterable): 空の集合、またはiterableの要素を集めた集合
+func builtinSet(args ...object.Object) object.Object {
+    res := object.NewSet()
+    if len(args) == 0 {
+        return res
+    }
+    if len(args) != 1 {
+        return wrongNumberOfArgs(len(args), "0..1")
+    }
+
+    elems, err := iterableElems(args[0])
+    if err != nil {
+        return err
+    }
+    for _, elem := range elems {
+        hk, ok := hashable(elem)
+        if !ok {
+            return newErrorKind(typeError, "unusable as set element: %s", elem.Type())
+        }
+        res.Add(hk)
+    }
+    return res
+}
+
+// add(s, elem): elemを加えた新しい集合
+func builtinAdd(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return wrongNumberOfArgs(len(args), "2")
+    }
+    s, ok := args[0].(*object.Set)
+    if !ok {
+        return argTypeError("add", args[0], object.SET_OBJ)
+    }
+    elem, err := setElem(args)
+    if err != nil {
+        return err
+    }
+
+    res := copySet(s)
+    res.Add(elem)
+    return res
+}
+
+// remove(s, elem): elemを取り除いた新しい集合
+func builtinRemove(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return wrongNumberOfArgs(len(args), "2")
+    }
+    s, ok := args[0].(*object.Set)
+    if !ok {
+        return argTypeError("remove", args[0], object.SET_OBJ)
+    }
+    elem, err := setElem(args)
+    if err != nil {
+        return err
+    }
+
+    res := copySet(s)
+    res.Remove(elem)
+    return res
+}
+
+// union(a, b, ...): いずれかの集合に含まれる要素. 最初に現れた順に並ぶ
+func builtinUnion(args ...object.Object) object.Object {
+    sets, err := setArgs("union", args)
+    if err != nil {
+        return err
+    }
+
+    res := object.NewSet()
+    for _, s := range sets {
+        for _, elem := range s.Elems() {
+            res.Add(elem.(object.Hashable))
+        }
+    }
+    return res
+}
+
+// intersection(a, b, ...): 全ての集合に含まれる要素. aの順に並ぶ
+func builtinIntersection(args ...object.Object) object.Object {
+    sets, err := setArgs("intersection", args)
+    if err != nil {
+        return err
+    }
+
+    res := object.NewSet()
+    for _, elem := range sets[0].Elems() {
+        hk := elem.(object.Hashable)
+        all := true
+        for _, s := range sets[1:] {
+            if !s.Has(hk) {
+                all = false
+                break
+            }
+        }
+        if all {
+            res.Add(hk)
+        }
+    }
+    return res
+}
+
+// difference(a, b, ...): aに含まれ、残りのどの集合にも含まれない要素
+func builtinDifference(args ...object.Object) object.Object {
+    sets, err := setArgs("difference", args)
+    if err != nil {
+        return err
+    }
+
+    res := copySet(sets[0])
+    for _, s := range sets[1:] {
+        for _, elem := range s.Elems() {
+            res.Remove(elem.(object.Hashable))
+        }
+    }
+    return res
+}
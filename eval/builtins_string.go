@@ -0,0 +1,366 @@
+package eval
+
+import (
+    "fmt"
+    "strings"
+    "unicode/utf8"
+    "monkey_interpreter/object"
+)
+
+// 文字列を扱うbuiltin関数. 位置や長さは全てバイトではなく文字(rune)単位で数える
+func init() {
+    builtins["split"] = &object.Builtin{Fn: builtinSplit}
+    builtins["join"] = &object.Builtin{Fn: builtinJoin}
+    builtins["trim"] = &object.Builtin{Fn: builtinTrim("trim", strings.Trim, strings.TrimSpace)}
+    builtins["trim_start"] = &object.Builtin{Fn: builtinTrim("trim_start", strings.TrimLeft, func(s string) string {
+        return strings.TrimLeftFunc(s, isSpace)
+    })}
+    builtins["trim_end"] = &object.Builtin{Fn: builtinTrim("trim_end", strings.TrimRight, func(s string) string {
+        return strings.TrimRightFunc(s, isSpace)
+    })}
+    builtins["upper"] = &object.Builtin{Fn: stringFunc("upper", strings.ToUpper)}
+    builtins["lower"] = &object.Builtin{Fn: stringFunc("lower", strings.ToLower)}
+    builtins["contains"] = &object.Builtin{Fn: stringPredicate("contains", strings.Contains)}
+    builtins["starts_with"] = &object.Builtin{Fn: stringPredicate("starts_with", strings.HasPrefix)}
+    builtins["ends_with"] = &object.Builtin{Fn: stringPredicate("ends_with", strings.HasSuffix)}
+    builtins["index_of"] = &object.Builtin{Fn: builtinIndexOf}
+    builtins["replace"] = &object.Builtin{Fn: builtinReplace}
+    builtins["repeat"] = &object.Builtin{Fn: builtinRepeat}
+    builtins["pad_start"] = &object.Builtin{Fn: builtinPad("pad_start", true)}
+    builtins["pad_end"] = &object.Builtin{Fn: builtinPad("pad_end", false)}
+    builtins["ord"] = &object.Builtin{Fn: builtinOrd}
+    builtins["chr"] = &object.Builtin{Fn: builtinChr}
+    builtins["format"] = &object.Builtin{Fn: builtinFormat}
+}
+
+// repeatやpadで作る文字列のバイト数の上限
+const maxStringLen = 1 << 30
+
+// sをn回繰り返す. 結果がmaxStringLenを超える場合はエラーを返す
+func repeatString(name, s string, n int64) (string, *object.Error) {
+    if n > 0 && int64(len(s)) > maxStringLen / n {
+        return "", newErrorKind(valueError, "result of `%s` is too long", name)
+    }
+    return strings.Repeat(s, int(n)), nil
+}
+
+func isSpace(r rune) bool {
+    return strings.TrimSpace(string(r)) == ""
+}
+
+func wrongNumberOfArgs(got int, want string) *object.Error {
+    return newErrorKind(argumentError, "wrong number of arguments. got=%d, want=%s", got, want)
+}
+
+func argTypeError(name string, arg object.Object, want object.ObjectType) *object.Error {
+    return newErrorKind(typeError, "argument to `%s` must be %s, got %s", name, want, arg.Type())
+}
+
+// argsが全て文字列であればその値を返す
+func stringValues(name string, args []object.Object) ([]string, *object.Error) {
+    values := make([]string, len(args))
+    for i, arg := range args {
+        s, ok := arg.(*object.String)
+        if !ok {
+            return nil, argTypeError(name, arg, object.STRING_OBJ)
+        }
+        values[i] = s.Value
+    }
+    return values, nil
+}
+
+// 文字列1つを受け取り文字列を返すbuiltin関数を作る
+func stringFunc(name string, f func(string) string) object.BuiltinFunction {
+    return func(args ...object.Object) object.Object {
+        if len(args) != 1 {
+            return wrongNumberOfArgs(len(args), "1")
+        }
+        s, err := stringValues(name, args)
+        if err != nil {
+            return err
+        }
+        return &object.String{Value: f(s[0])}
+    }
+}
+
+// 文字列2つを受け取り真偽値を返すbuiltin関数を作る
+func stringPredicate(name string, f func(string, string) bool) object.BuiltinFunction {
+    return func(args ...object.Object) object.Object {
+        if len(args) != 2 {
+            return wrongNumberOfArgs(len(args), "2")
+        }
+        s, err := stringValues(name, args)
+        if err != nil {
+            return err
+        }
+        return nativeBoolToBooleanObject(f(s[0], s[1]))
+    }
+}
+
+// split(s, sep): sepで区切った文字列の配列. sepを省略すると空白で、空文字列では1文字ずつ区切る
+func builtinSplit(args ...object.Object) object.Object {
+    if len(args) != 1 && len(args) != 2 {
+        return wrongNumberOfArgs(len(args), "1..2")
+    }
+    s, err := stringValues("split", args)
+    if err != nil {
+        return err
+    }
+
+    var parts []string
+    if len(s) == 1 {
+        parts = strings.Fields(s[0])
+    } else {
+        parts = strings.Split(s[0], s[1])
+    }
+
+    elems := make([]object.Object, len(parts))
+    for i, part := range parts {
+        elems[i] = &object.String{Value: part}
+    }
+    return &object.Array{Elems: elems}
+}
+
+// join(arr, sep): 文字列の配列をsepでつなげる
+func builtinJoin(args ...object.Object) object.Object {
+    if len(args) != 1 && len(args) != 2 {
+        return wrongNumberOfArgs(len(args), "1..2")
+    }
+    arr, ok := args[0].(*object.Array)
+    if !ok {
+        return argTypeError("join", args[0], object.ARRAY_OBJ)
+    }
+    sep := ""
+    if len(args) == 2 {
+        s, err := stringValues("join", args[1:])
+        if err != nil {
+            return err
+        }
+        sep = s[0]
+    }
+
+    parts, err := stringValues("join", arr.Elems)
+    if err != nil {
+        return err
+    }
+    return &object.String{Value: strings.Join(parts, sep)}
+}
+
+// trim(s, cutset): 両端からcutsetに含まれる文字を取り除く. cutsetを省略すると空白を取り除く
+func builtinTrim(name string, withCutset func(string, string) string, space func(string) string) object.BuiltinFunction {
+    return func(args ...object.Object) object.Object {
+        if len(args) != 1 && len(args) != 2 {
+            return wrongNumberOfArgs(len(args), "1..2")
+        }
+        s, err := stringValues(name, args)
+        if err != nil {
+            return err
+        }
+
+        if len(s) == 1 {
+            return &object.String{Value: space(s[0])}
+        }
+        return &object.String{Value: withCutset(s[0], s[1])}
+    }
+}
+
+// index_of(s, sub): subが最初に現れる位置. 見つからなければ-1
+func builtinIndexOf(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return wrongNumberOfArgs(len(args), "2")
+    }
+    s, err := stringValues("index_of", args)
+    if err != nil {
+        return err
+    }
+
+    i := strings.Index(s[0], s[1])
+    if i < 0 {
+        return &object.Integer{Value: -1}
+    }
+    return &object.Integer{Value: int64(utf8.RuneCountInString(s[0][:i]))}
+}
+
+// replace(s, old, new, n): oldを先頭からn個までnewに置き換える. nを省略すると全て置き換える
+func builtinReplace(args ...object.Object) object.Object {
+    if len(args) != 3 && len(args) != 4 {
+        return wrongNumberOfArgs(len(args), "3..4")
+    }
+    s, err := stringValues("replace", args[:3])
+    if err != nil {
+        return err
+    }
+
+    n := -1
+    if len(args) == 4 {
+        i, ok := args[3].(*object.Integer)
+        if !ok {
+            return argTypeError("replace", args[3], object.INTEGER_OBJ)
+        }
+        n = int(i.Value)
+    }
+    return &object.String{Value: strings.Replace(s[0], s[1], s[2], n)}
+}
+
+// repeat(s, n): sをn回繰り返す
+func builtinRepeat(args ...object.Object) object.Object {
+    if len(args) != 2 {
+        return wrongNumberOfArgs(len(args), "2")
+    }
+    s, err := stringValues("repeat", args[:1])
+    if err != nil {
+        return err
+    }
+    n, ok := args[1].(*object.Integer)
+    if !ok {
+        return argTypeError("repeat", args[1], object.INTEGER_OBJ)
+    }
+    if n.Value < 0 {
+        return newErrorKind(valueError, "negative repeat count: %d", n.Value)
+    }
+    res, err := repeatString("repeat", s[0], n.Value)
+    if err != nil {
+        return err
+    }
+    return &object.String{Value: res}
+}
+
+// pad_start(s, width, fill), pad_end(s, width, fill): 文字数がwidthになるまでfillで埋める.
+// fillは1文字で、省略すると空白
+func builtinPad(name string, atStart bool) object.BuiltinFunction {
+    return func(args ...object.Object) object.Object {
+        if len(args) != 2 && len(args) != 3 {
+            return wrongNumberOfArgs(len(args), "2..3")
+        }
+        s, ok := args[0].(*object.String)
+        if !ok {
+            return argTypeError(name, args[0], object.STRING_OBJ)
+        }
+        width, ok := args[1].(*object.Integer)
+        if !ok {
+            return argTypeError(name, args[1], object.INTEGER_OBJ)
+        }
+        fill := " "
+        if len(args) == 3 {
+            f, ok := args[2].(*object.String)
+            if !ok {
+                return argTypeError(name, args[2], object.STRING_OBJ)
+            }
+            if utf8.RuneCountInString(f.Value) != 1 {
+                return newErrorKind(valueError, "fill of `%s` must be a single character, got %q", name, f.Value)
+            }
+            fill = f.Value
+        }
+
+        count := int64(utf8.RuneCountInString(s.Value))
+        if width.Value <= count {
+            return s
+        }
+        pad, err := repeatString(name, fill, width.Value - count)
+        if err != nil {
+            return err
+        }
+        if atStart {
+            return &object.String{Value: pad + s.Value}
+        }
+        return &object.String{Value: s.Value + pad}
+    }
+}
+
+// ord(c): 1文字の文字列のUnicodeコードポイント
+func builtinOrd(args ...object.Object) object.Object {
+    if len(args) != 1 {
+        return wrongNumberOfArgs(len(args), "1")
+    }
+    s, err := stringValues("ord", args)
+    if err != nil {
+        return err
+    }
+    if utf8.RuneCountInString(s[0]) != 1 {
+        return newErrorKind(valueError, "argument to `ord` must be a single character, got %q", s[0])
+    }
+    r, _ := utf8.DecodeRuneInString(s[0])
+    return &object.Integer{Value: int64(r)}
+}
+
+// chr(n): コードポイントnの1文字の文字列
+func builtinChr(args ...object.Object) object.Object {
+    if len(args) != 1 {
+        return wrongNumberOfArgs(len(args), "1")
+    }
+    n, ok := args[0].(*object.Integer)
+    if !ok {
+        return argTypeError("chr", args[0], object.INTEGER_OBJ)
+    }
+    if n.Value < 0 || n.Value > utf8.MaxRune || !utf8.ValidRune(rune(n.Value)) {
+        return newErrorKind(valueError, "invalid code point: %d", n.Value)
+    }
+    return &object.String{Value: string(rune(n.Value))}
+}
+
+// format(f, args...): fの書式指定を順に引数で置き換える.
+// %d, %x, %o, %bは整数, %sは文字列(それ以外はInspectの結果), %vは任意の値, %%は`%`そのもの.
+// `%`の直後には`-`と`0`のフラグと幅を書ける
+func builtinFormat(args ...object.Object) object.Object {
+    if len(args) < 1 {
+        return wrongNumberOfArgs(len(args), "1+")
+    }
+    f, ok := args[0].(*object.String)
+    if !ok {
+        return argTypeError("format", args[0], object.STRING_OBJ)
+    }
+    rest := args[1:]
+
+    var out strings.Builder
+    runes := []rune(f.Value)
+    for i := 0; i < len(runes); i++ {
+        if runes[i] != '%' {
+            out.WriteRune(runes[i])
+            continue
+        }
+
+        // %から書式指定の文字までを読む
+        j := i + 1
+        for j < len(runes) && strings.ContainsRune("-0123456789", runes[j]) {
+            j++
+        }
+        if j == len(runes) {
+            return newErrorKind(valueError, "format: incomplete verb at end of %q", f.Value)
+        }
+        spec, verb := string(runes[i:j]), runes[j]
+        i = j
+
+        if verb == '%' {
+            out.WriteRune('%')
+            continue
+        }
+        if len(rest) == 0 {
+            return newErrorKind(argumentError, "format: missing argument for %s%c", spec, verb)
+        }
+        arg := rest[0]
+        rest = rest[1:]
+
+        switch verb {
+        case 'd', 'x', 'o', 'b':
+            if !isInteger(arg) {
+                return newErrorKind(typeError, "format: %s%c expects INTEGER, got %s", spec, verb, arg.Type())
+            }
+            out.WriteString(fmt.Sprintf(spec + string(verb), toBigInt(arg)))
+        case 's':
+            if s, ok := arg.(*object.String); ok {
+                out.WriteString(fmt.Sprintf(spec + "s", s.Value))
+            } else {
+                out.WriteString(fmt.Sprintf(spec + "s", arg.Inspect()))
+            }
+        case 'v':
+            out.WriteString(fmt.Sprintf(spec + "s", arg.Inspect()))
+        default:
+            return newErrorKind(valueError, "format: unknown verb %s%c", spec, verb)
+        }
+    }
+
+    if len(rest) > 0 {
+        return newErrorKind(argumentError, "format: %d unused arguments", len(rest))
+    }
+    return &object.String{Value: out.String()}
+}
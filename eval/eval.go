@@ -2,14 +2,19 @@ package eval
 
 import (
     "fmt"
+    "math"
+    "math/big"
     "monkey_interpreter/ast"
     "monkey_interpreter/object"
+    "monkey_interpreter/token"
 )
 
 var (
     TRUE = &object.Boolean{Value: true}
     FALSE = &object.Boolean{Value: false}
     NULL = &object.Null{}
+    BREAK = &object.Break{}
+    CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Env) object.Object {
@@ -22,7 +27,9 @@ func Eval(node ast.Node, env *object.Env) object.Object {
         if isError(val) {
             return val
         }
-        env.Set(node.Name.Value, val)
+        if err := evalLetBinding(node, val, env); err != nil {
+            return withPosition(err, node.Token)
+        }
 
     case *ast.ReturnStatement:
         val := Eval(node.ReturnValue, env)
@@ -31,13 +38,31 @@ func Eval(node ast.Node, env *object.Env) object.Object {
         }
         return &object.ReturnValue{Value: val}
 
+    case *ast.ThrowStatement:
+        return evalThrowStatement(node, env)
+
     case *ast.ExpressionStatement:
         return Eval(node.Expression, env)
 
     case *ast.BlockStatement:
         return evalBlockStatement(node, env)
 
+    case *ast.WhileStatement:
+        return evalWhileStatement(node, env)
+
+    case *ast.ForStatement:
+        return withPosition(evalForStatement(node, env), node.Token)
+
+    case *ast.BreakStatement:
+        return BREAK
+
+    case *ast.ContinueStatement:
+        return CONTINUE
+
     case *ast.IntegerLiteral:
+        if node.Big != nil {
+            return &object.BigInteger{Value: new(big.Int).Set(node.Big)}
+        }
         return &object.Integer{Value: node.Value}
 
     case *ast.StringLiteral:
@@ -53,22 +78,14 @@ func Eval(node ast.Node, env *object.Env) object.Object {
             return b
         }
 
-        return newError("identifier not found: " + node.Value)
+        return withPosition(newErrorKind(nameError, "identifier not found: %s", node.Value), node.Token)
 
     case *ast.FunctionLiteral:
         return &object.Function{Params: node.Params, Body: node.Body, Env: env}
 
     case *ast.FunctionCall:
-        f := Eval(node.Func, env)
-        if isError(f) {
-            return f
-        }
-        args := evalExpressions(node.Args, env)
-        if len(args) == 1 && isError(args[0]) {
-            return args[0]
-        }
-
-        return applyFunction(f, args)
+        obj, _ := evalChain(node, env)
+        return obj
 
     case *ast.Boolean:
         if node.Value {
@@ -76,6 +93,12 @@ func Eval(node ast.Node, env *object.Env) object.Object {
         }
         return FALSE
 
+    case *ast.AssignExpression:
+        return withPosition(evalAssignExpression(node, env), node.Token)
+
+    case *ast.TryExpression:
+        return evalTryExpression(node, env)
+
     case *ast.IfExpression:
         cond := Eval(node.Cond, env)
         if isError(cond) {
@@ -91,28 +114,61 @@ func Eval(node ast.Node, env *object.Env) object.Object {
         }
         return NULL
 
+    case *ast.MatchExpression:
+        return withPosition(evalMatchExpression(node, env), node.Token)
+
     case *ast.ArrayLiteral:
         a := &object.Array{}
         elems := evalExpressions(node.Elems, env)
+        if len(elems) == 1 && isError(elems[0]) {
+            return elems[0]
+        }
         a.Elems = elems
         return a
 
     case *ast.IndexExpression:
-        left := Eval(node.Left, env)
-        index := Eval(node.Index, env)
-        return evalIndexExpression(left, index)
+        obj, _ := evalChain(node, env)
+        return obj
+
+    case *ast.SliceExpression:
+        obj, _ := evalChain(node, env)
+        return obj
+
+    case *ast.TernaryExpression:
+        cond := Eval(node.Cond, env)
+        if isError(cond) {
+            return cond
+        }
+
+        if isTruthly(cond) {
+            return Eval(node.Cons, env)
+        }
+        return Eval(node.Alt, env)
 
     case *ast.HashLiteral:
-        return evalHashLiteral(node, env)
+        return withPosition(evalHashLiteral(node, env), node.Token)
+
+    case *ast.SetLiteral:
+        return withPosition(evalSetLiteral(node, env), node.Token)
+
+    case *ast.ArrayComprehension:
+        return withPosition(evalArrayComprehension(node, env), node.Token)
+
+    case *ast.HashComprehension:
+        return withPosition(evalHashComprehension(node, env), node.Token)
 
     case *ast.PrefixExpression:
         right := Eval(node.Right, env)
         if isError(right) {
             return right
         }
-        return evalPrefixExpression(node.Operator, right)
+        return withPosition(evalPrefixExpression(node.Operator, right), node.Token)
 
     case *ast.InfixExpression:
+        if node.Operator == "&&" || node.Operator == "||" || node.Operator == "??" {
+            return evalLogicalExpression(node, env)
+        }
+
         left := Eval(node.Left, env)
         if isError(left) {
             return left
@@ -124,24 +180,150 @@ func Eval(node ast.Node, env *object.Env) object.Object {
         }
 
         op := node.Operator
-        return evalInfixExpression(op, left, right)
+        return withPosition(evalInfixExpression(op, left, right), node.Token)
     }
 
     return nil
 }
 
+func evalLetBinding(ls *ast.LetStatement, val object.Object, env *object.Env) *object.Error {
+    var bindings []binding
+    if ls.Pattern != nil {
+        var err *object.Error
+        bindings, err = destructure(ls.Pattern, val, nil)
+        if err != nil {
+            return err
+        }
+    } else {
+        bindings = []binding{{name: ls.Name.Value, val: val}}
+    }
+
+    for _, b := range bindings {
+        if env.IsConst(b.name) {
+            return newError("cannot redeclare constant %s", b.name)
+        }
+    }
+
+    for _, b := range bindings {
+        if ls.Token.Type == token.CONST {
+            env.SetConst(b.name, b.val)
+        } else {
+            env.Set(b.name, b.val)
+        }
+    }
+    return nil
+}
+
+// throwされた値をエラーとして伝播させる. 値としてのエラーはそのまま伝播するエラーに戻り、
+// 文字列はそのままメッセージとなる. ハッシュからはmessage, kindなどのキーを引き継ぐ
+func evalThrowStatement(ts *ast.ThrowStatement, env *object.Env) object.Object {
+    val := Eval(ts.Value, env)
+    if isError(val) {
+        return val
+    }
+
+    if e, ok := val.(*object.Error); ok {
+        err := *e
+        err.IsValue = false
+        return withPosition(&err, ts.Token)
+    }
+
+    err := &object.Error{Kind: genericError, Msg: val.Inspect(), Value: val}
+    if h, ok := val.(*object.Hash); ok {
+        if msg, ok := hashField(h, "message").(*object.String); ok {
+            err.Msg = msg.Value
+        }
+        if kind, ok := hashField(h, "kind").(*object.String); ok {
+            err.Kind = kind.Value
+        }
+        if line, ok := hashField(h, "line").(*object.Integer); ok {
+            err.Line = int(line.Value)
+        }
+        if column, ok := hashField(h, "column").(*object.Integer); ok {
+            err.Column = int(column.Value)
+        }
+        if v := hashField(h, "value"); v != nil && v != NULL {
+            err.Value = v
+        }
+    }
+
+    return withPosition(err, ts.Token)
+}
+
+// try節で発生したエラーをcatch節で受け取る. finally節は常に最後に評価され、
+// finally節自身がエラーやreturnで抜けた場合はそれがtry式全体の結果となる
+func evalTryExpression(te *ast.TryExpression, env *object.Env) object.Object {
+    res := Eval(te.Body, env)
+
+    if isError(res) && te.Catch != nil {
+        bindings, derr := destructure(te.CatchParam, errorValue(res.(*object.Error)), nil)
+        if derr != nil {
+            res = withPosition(derr, te.Token)
+        } else {
+            catchEnv := object.NewEnclosedEnv(env)
+            for _, b := range bindings {
+                catchEnv.Set(b.name, b.val)
+            }
+            res = Eval(te.Catch, catchEnv)
+        }
+    }
+
+    if te.Finally != nil {
+        fin := Eval(te.Finally, env)
+        if isError(fin) {
+            return fin
+        }
+        if fin != nil {
+            switch fin.Type() {
+            case object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+                return fin
+            }
+        }
+    }
+
+    return res
+}
+
+// エラーの添字アクセスと分割代入のため、エラーをmessage, kind, line, column, valueをキーとするハッシュに変換する
+func errorHash(err *object.Error) *object.Hash {
+    var value object.Object = NULL
+    if err.Value != nil {
+        value = err.Value
+    }
+
+    h := object.NewHash()
+    setHashField(h, "message", &object.String{Value: err.Msg})
+    setHashField(h, "kind", &object.String{Value: err.Kind})
+    setHashField(h, "line", &object.Integer{Value: int64(err.Line)})
+    setHashField(h, "column", &object.Integer{Value: int64(err.Column)})
+    setHashField(h, "value", value)
+    return h
+}
+
+func hashField(h *object.Hash, name string) object.Object {
+    pair, ok := h.Get(&object.String{Value: name})
+    if !ok {
+        return nil
+    }
+    return pair.Value
+}
+
+func setHashField(h *object.Hash, name string, val object.Object) {
+    h.Set(&object.String{Value: name}, val)
+}
+
 func evalProgram(program *ast.Program, env *object.Env) object.Object {
     var res object.Object
 
     for _, stmt := range program.Statements {
         res = Eval(stmt, env)
 
-        switch res := res.(type) {
-        case *object.ReturnValue:
-            return res.Value
-        case *object.Error:
+        if isError(res) {
             return res
         }
+        if rv, ok := res.(*object.ReturnValue); ok {
+            return rv.Value
+        }
     }
 
     return res
@@ -153,9 +335,12 @@ func evalBlockStatement(bs *ast.BlockStatement, env *object.Env) object.Object {
     for _, stmt := range bs.Statements {
         res = Eval(stmt, env)
 
+        if isError(res) {
+            return res
+        }
         if res != nil {
-            rt := res.Type()
-            if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+            switch res.Type() {
+            case object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
                 return res
             }
         }
@@ -163,6 +348,239 @@ func evalBlockStatement(bs *ast.BlockStatement, env *object.Env) object.Object {
     return res
 }
 
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Env) object.Object {
+    for {
+        cond := Eval(ws.Cond, env)
+        if isError(cond) {
+            return cond
+        }
+        if !isTruthly(cond) {
+            return NULL
+        }
+
+        // 本体は反復ごとに新しい環境で評価する
+        res := Eval(ws.Body, object.NewEnclosedEnv(env))
+        if res, stop := loopControl(res); stop {
+            return res
+        }
+    }
+}
+
+func evalForStatement(fs *ast.ForStatement, env *object.Env) object.Object {
+    iterable := Eval(fs.Iterable, env)
+    if isError(iterable) {
+        return iterable
+    }
+
+    next, err := newIterator(iterable)
+    if err != nil {
+        return err
+    }
+
+    for {
+        elem, ok := next()
+        if !ok {
+            return NULL
+        }
+
+        loopEnv := object.NewEnclosedEnv(env)
+        loopEnv.Set(fs.Var.Value, elem)
+
+        res := Eval(fs.Body, loopEnv)
+        if res, stop := loopControl(res); stop {
+            return res
+        }
+    }
+}
+
+// ループ本体の評価結果から、ループを抜けるべきかとその時の値を返す
+func loopControl(res object.Object) (object.Object, bool) {
+    if res == nil {
+        return nil, false
+    }
+
+    if isError(res) {
+        return res, true
+    }
+    switch res.Type() {
+    case object.BREAK_OBJ:
+        return NULL, true
+    case object.RETURN_VALUE_OBJ:
+        return res, true
+    }
+    return nil, false
+}
+
+// for文で反復できるオブジェクトから、要素を1つずつ返す関数を作る。
+// 配列は要素、ハッシュはキー(挿入順)、文字列は1文字ずつの文字列、整数nは0からn-1までを返す
+func newIterator(obj object.Object) (func() (object.Object, bool), *object.Error) {
+    var elems []object.Object
+
+    switch obj := obj.(type) {
+    case *object.Array:
+        elems = obj.Elems
+    case *object.Hash:
+        for _, pair := range obj.Pairs() {
+            elems = append(elems, pair.Key)
+        }
+    case *object.Set:
+        elems = obj.Elems()
+    case *object.String:
+        for _, r := range obj.Value {
+            elems = append(elems, &object.String{Value: string(r)})
+        }
+    case *object.Integer:
+        return newIterator(&object.Range{Stop: obj.Value, Step: 1})
+    case *object.Range:
+        var i uint64
+        return func() (object.Object, bool) {
+            v, ok := obj.At(i)
+            if !ok {
+                return nil, false
+            }
+            i++
+            return &object.Integer{Value: v}, true
+        }, nil
+    default:
+        return nil, newErrorKind(typeError, "%s is not iterable", obj.Type())
+    }
+
+    var i int
+    return func() (object.Object, bool) {
+        if i >= len(elems) {
+            return nil, false
+        }
+        i++
+        return elems[i-1], true
+    }, nil
+}
+
+// for節で2つの変数を受け取る場合の反復. ハッシュはキーと値、それ以外は添字と要素を返す
+func newPairIterator(obj object.Object) (func() (object.Object, object.Object, bool), *object.Error) {
+    if h, ok := obj.(*object.Hash); ok {
+        pairs := h.Pairs()
+
+        var i int
+        return func() (object.Object, object.Object, bool) {
+            if i >= len(pairs) {
+                return nil, nil, false
+            }
+            i++
+            return pairs[i-1].Key, pairs[i-1].Value, true
+        }, nil
+    }
+
+    next, err := newIterator(obj)
+    if err != nil {
+        return nil, err
+    }
+    var i int64
+    return func() (object.Object, object.Object, bool) {
+        elem, ok := next()
+        if !ok {
+            return nil, nil, false
+        }
+        i++
+        return &object.Integer{Value: i - 1}, elem, true
+    }, nil
+}
+
+// for節の各反復でループ変数を束縛した環境を作り、条件を満たせばyieldを呼ぶ
+func evalComprehension(cc *ast.ComprehensionClause, env *object.Env, yield func(*object.Env) object.Object) object.Object {
+    iterable := Eval(cc.Iterable, env)
+    if isError(iterable) {
+        return iterable
+    }
+
+    next, err := newPairIterator(iterable)
+    if err != nil {
+        return err
+    }
+
+    for key, val, ok := next(); ok; key, val, ok = next() {
+        var bindings []binding
+        var err *object.Error
+        if cc.Key != nil {
+            bindings, err = destructure(cc.Key, key, bindings)
+            if err != nil {
+                return err
+            }
+        } else if _, isHash := iterable.(*object.Hash); isHash {
+            // 変数が1つの場合はfor文と同じくハッシュのキーを受け取る
+            val = key
+        }
+        bindings, err = destructure(cc.Value, val, bindings)
+        if err != nil {
+            return err
+        }
+
+        scope := object.NewEnclosedEnv(env)
+        for _, b := range bindings {
+            scope.Set(b.name, b.val)
+        }
+
+        if cc.Cond != nil {
+            cond := Eval(cc.Cond, scope)
+            if isError(cond) {
+                return cond
+            }
+            if !isTruthly(cond) {
+                continue
+            }
+        }
+
+        if res := yield(scope); isError(res) {
+            return res
+        }
+    }
+
+    return nil
+}
+
+func evalArrayComprehension(ac *ast.ArrayComprehension, env *object.Env) object.Object {
+    elems := []object.Object{}
+
+    res := evalComprehension(ac.Clause, env, func(scope *object.Env) object.Object {
+        elem := Eval(ac.Elem, scope)
+        if isError(elem) {
+            return elem
+        }
+        elems = append(elems, elem)
+        return nil
+    })
+    if isError(res) {
+        return res
+    }
+
+    return &object.Array{Elems: elems}
+}
+
+func evalHashComprehension(hc *ast.HashComprehension, env *object.Env) object.Object {
+    h := object.NewHash()
+
+    res := evalComprehension(hc.Clause, env, func(scope *object.Env) object.Object {
+        key := Eval(hc.Key, scope)
+        if isError(key) {
+            return key
+        }
+        hk, ok := hashable(key)
+        if !ok {
+            return newErrorKind(typeError, "unusable as hash key: %s", key.Type())
+        }
+        val := Eval(hc.Value, scope)
+        if isError(val) {
+            return val
+        }
+        h.Set(hk, val)
+        return nil
+    })
+    if isError(res) {
+        return res
+    }
+
+    return h
+}
+
 func evalExpressions(exps []ast.Expression, env *object.Env) []object.Object {
     args := []object.Object{}
     for _, exp := range exps {
@@ -175,6 +593,31 @@ func evalExpressions(exps []ast.Expression, env *object.Env) []object.Object {
     return args
 }
 
+// 位置で渡す引数と名前付き引数をそれぞれ評価する
+func evalArguments(exps []ast.Expression, env *object.Env) ([]object.Object, []binding, object.Object) {
+    args := []object.Object{}
+    var named []binding
+
+    for _, exp := range exps {
+        if na, ok := exp.(*ast.NamedArgument); ok {
+            val := Eval(na.Value, env)
+            if isError(val) {
+                return nil, nil, val
+            }
+            named = append(named, binding{name: na.Name.Value, val: val})
+            continue
+        }
+
+        val := Eval(exp, env)
+        if isError(val) {
+            return nil, nil, val
+        }
+        args = append(args, val)
+    }
+
+    return args, named, nil
+}
+
 func evalPrefixExpression(op string, right object.Object) object.Object {
     if (op == "!") {
         return evalBangOperatorExpression(right)
@@ -182,34 +625,106 @@ func evalPrefixExpression(op string, right object.Object) object.Object {
     if (op == "-") {
         return evalMinusPrefixOperatorExpression(right)
     }
-    return newError("unknown operator: %s%s", op, right.Type())
+    return newErrorKind(typeError, "unknown operator: %s%s", op, right.Type())
+}
+
+// 左辺で結果が決まる場合は右辺を評価せず、結果を決めたオペランドをそのまま返す
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Env) object.Object {
+    left := Eval(node.Left, env)
+    if isError(left) {
+        return left
+    }
+
+    // ??は左辺がnullの場合にのみ右辺を評価する
+    if node.Operator == "??" {
+        if left != NULL {
+            return left
+        }
+    } else if isTruthly(left) == (node.Operator == "||") {
+        return left
+    }
+
+    return Eval(node.Right, env)
 }
 
 func evalInfixExpression(op string, left, right object.Object) object.Object {
     switch {
+    case op == ".." || op == "..<":
+        return evalRangeExpression(op, left, right)
     case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
         return evalIntegerInfixExpression(op, left, right)
+    case isInteger(left) && isInteger(right):
+        return evalBigIntegerInfixExpression(op, left, right)
     case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
         return evalStringInfixExpression(op, left, right)
+    case op == "==":
+        return nativeBoolToBooleanObject(object.Equals(left, right))
+    case op == "!=":
+        return nativeBoolToBooleanObject(!object.Equals(left, right))
     case left.Type() != right.Type():
-        return newError("type mismatch: %s %s %s", left.Type(), op, right.Type())
+        return newErrorKind(typeError, "type mismatch: %s %s %s", left.Type(), op, right.Type())
     default:
-        return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+        return newErrorKind(typeError, "unknown operator: %s %s %s", left.Type(), op, right.Type())
+    }
+}
+
+// start..endはendを含み、start..<endは含まない範囲を作る
+func evalRangeExpression(op string, left, right object.Object) object.Object {
+    start, ok := left.(*object.Integer)
+    if !ok {
+        return newErrorKind(typeError, "range bounds must be INTEGER, got %s %s %s", left.Type(), op, right.Type())
+    }
+    end, ok := right.(*object.Integer)
+    if !ok {
+        return newErrorKind(typeError, "range bounds must be INTEGER, got %s %s %s", left.Type(), op, right.Type())
     }
+
+    if op == "..<" {
+        return &object.Range{Start: start.Value, Stop: end.Value, Step: 1}
+    }
+    if end.Value == math.MaxInt64 {
+        return newErrorKind(valueError, "range end overflows: %d", end.Value)
+    }
+    return &object.Range{Start: start.Value, Stop: end.Value + 1, Step: 1, Inclusive: true}
 }
 
 func evalIntegerInfixExpression(op string, left, right object.Object) object.Object {
     lval := left.(*object.Integer).Value
     rval := right.(*object.Integer).Value
+    // オーバーフローする場合はBigIntegerで計算し直す
     switch op {
     case "+":
-        return &object.Integer{Value: lval + rval}
+        if sum := lval + rval; (sum > lval) == (rval > 0) {
+            return &object.Integer{Value: sum}
+        }
+        return evalBigIntegerInfixExpression(op, left, right)
     case "-":
-        return &object.Integer{Value: lval - rval}
+        if diff := lval - rval; (diff < lval) == (rval > 0) {
+            return &object.Integer{Value: diff}
+        }
+        return evalBigIntegerInfixExpression(op, left, right)
     case "*":
-        return &object.Integer{Value: lval * rval}
-    case "/":
-        return &object.Integer{Value: lval / rval}
+        prod := lval * rval
+        if lval == 0 || (prod / lval == rval && !(lval == -1 && rval == math.MinInt64)) {
+            return &object.Integer{Value: prod}
+        }
+        return evalBigIntegerInfixExpression(op, left, right)
+    case "/", "//", "%":
+        if rval == 0 {
+            return newErrorKind(zeroDivisionError, "division by zero")
+        }
+        if lval == math.MinInt64 && rval == -1 {
+            return evalBigIntegerInfixExpression(op, left, right)
+        }
+        // `/`と`%`は0方向、`//`は負の無限大方向へ丸める
+        switch op {
+        case "/":
+            return &object.Integer{Value: lval / rval}
+        case "//":
+            return &object.Integer{Value: floorDiv(lval, rval)}
+        default:
+            return &object.Integer{Value: lval % rval}
+        }
     case "==":
         return nativeBoolToBooleanObject(lval == rval)
     case "!=":
@@ -218,8 +733,56 @@ func evalIntegerInfixExpression(op string, left, right object.Object) object.Obj
         return nativeBoolToBooleanObject(lval < rval)
     case ">":
         return nativeBoolToBooleanObject(lval > rval)
+    case "<=":
+        return nativeBoolToBooleanObject(lval <= rval)
+    case ">=":
+        return nativeBoolToBooleanObject(lval >= rval)
+    default:
+        return newErrorKind(typeError, "unknown operator: %s %s %s", left.Type(), op, right.Type())
+    }
+}
+
+func evalBigIntegerInfixExpression(op string, left, right object.Object) object.Object {
+    lval := toBigInt(left)
+    rval := toBigInt(right)
+    switch op {
+    case "+":
+        return newIntegerObject(new(big.Int).Add(lval, rval))
+    case "-":
+        return newIntegerObject(new(big.Int).Sub(lval, rval))
+    case "*":
+        return newIntegerObject(new(big.Int).Mul(lval, rval))
+    case "/", "//", "%":
+        if rval.Sign() == 0 {
+            return newErrorKind(zeroDivisionError, "division by zero")
+        }
+        // Quo, Remは0方向への切り捨てで、int64の`/`, `%`と一致する
+        q, r := new(big.Int).QuoRem(lval, rval, new(big.Int))
+        switch op {
+        case "/":
+            return newIntegerObject(q)
+        case "//":
+            if r.Sign() != 0 && (r.Sign() < 0) != (rval.Sign() < 0) {
+                q.Sub(q, big.NewInt(1))
+            }
+            return newIntegerObject(q)
+        default:
+            return newIntegerObject(r)
+        }
+    case "==":
+        return nativeBoolToBooleanObject(lval.Cmp(rval) == 0)
+    case "!=":
+        return nativeBoolToBooleanObject(lval.Cmp(rval) != 0)
+    case "<":
+        return nativeBoolToBooleanObject(lval.Cmp(rval) < 0)
+    case ">":
+        return nativeBoolToBooleanObject(lval.Cmp(rval) > 0)
+    case "<=":
+        return nativeBoolToBooleanObject(lval.Cmp(rval) <= 0)
+    case ">=":
+        return nativeBoolToBooleanObject(lval.Cmp(rval) >= 0)
     default:
-        return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+        return newErrorKind(typeError, "unknown operator: %s %s %s", left.Type(), op, right.Type())
     }
 }
 
@@ -234,8 +797,16 @@ func evalStringInfixExpression(op string, left, right object.Object) object.Obje
         return nativeBoolToBooleanObject(lStr == rStr)
     case "!=":
         return nativeBoolToBooleanObject(lStr != rStr)
+    case "<":
+        return nativeBoolToBooleanObject(lStr < rStr)
+    case ">":
+        return nativeBoolToBooleanObject(lStr > rStr)
+    case "<=":
+        return nativeBoolToBooleanObject(lStr <= rStr)
+    case ">=":
+        return nativeBoolToBooleanObject(lStr >= rStr)
     default:
-        return newError("unknown operator: %s %s %s", left.Type(), op, right.Type())
+        return newErrorKind(typeError, "unknown operator: %s %s %s", left.Type(), op, right.Type())
     }
 }
 
@@ -253,56 +824,289 @@ func evalBangOperatorExpression(exp object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(exp object.Object) object.Object {
-    i, ok := exp.(*object.Integer)
-    if !ok {
-        return newError("unknown operator: -%s", exp.Type())
+    switch i := exp.(type) {
+    case *object.Integer:
+        if i.Value == math.MinInt64 {
+            return newIntegerObject(new(big.Int).Neg(toBigInt(i)))
+        }
+        return &object.Integer{Value: -i.Value}
+    case *object.BigInteger:
+        return newIntegerObject(new(big.Int).Neg(i.Value))
+    default:
+        return newErrorKind(typeError, "unknown operator: -%s", exp.Type())
     }
-
-    i.Value = -i.Value
-    return i
 }
 
 func evalArrayIndexExpression(left, index object.Object) object.Object {
     arr := left.(*object.Array)
     i := index.(*object.Integer)
 
-    if i.Value < 0 || len(arr.Elems) <= int(i.Value) {
+    idx, ok := normalizeIndex(i.Value, len(arr.Elems))
+    if !ok {
+        return NULL
+    }
+
+    return arr.Elems[idx]
+}
+
+// 文字列の添字は1文字(rune)単位で数える
+func evalStringIndexExpression(left, index object.Object) object.Object {
+    runes := []rune(left.(*object.String).Value)
+    i := index.(*object.Integer)
+
+    idx, ok := normalizeIndex(i.Value, len(runes))
+    if !ok {
+        return NULL
+    }
+
+    return &object.String{Value: string(runes[idx])}
+}
+
+func evalRangeIndexExpression(left, index object.Object) object.Object {
+    r := left.(*object.Range)
+    i := index.(*object.Integer).Value
+
+    // 負の添字は末尾からの位置. 要素数はint64に収まらないことがあるのでuint64で扱う
+    idx := uint64(i)
+    if i < 0 {
+        back := -uint64(i)
+        if back > r.Len() {
+            return NULL
+        }
+        idx = r.Len() - back
+    }
+    v, ok := r.At(idx)
+    if !ok {
         return NULL
     }
 
-    return arr.Elems[i.Value]
+    return &object.Integer{Value: v}
+}
+
+// 負の添字を末尾からの位置に読み替える. 範囲外であればfalseを返す
+func normalizeIndex(i int64, length int) (int, bool) {
+    if i < 0 {
+        i += int64(length)
+    }
+    if i < 0 || int64(length) <= i {
+        return 0, false
+    }
+    return int(i), true
+}
+
+// 添字、スライス、呼び出しの連鎖を評価する. `?.`の左辺がnullであれば、
+// 連鎖の残りを評価せずにnullとし、2つ目の戻り値で打ち切られたことを外側へ伝える
+func evalChain(node ast.Expression, env *object.Env) (object.Object, bool) {
+    switch node := node.(type) {
+    case *ast.IndexExpression:
+        left, short := evalChain(node.Left, env)
+        if short || isError(left) {
+            return left, short
+        }
+        if node.Optional && left == NULL {
+            return NULL, true
+        }
+        index := Eval(node.Index, env)
+        if isError(index) {
+            return index, false
+        }
+        return withPosition(evalIndexExpression(left, index), node.Token), false
+
+    case *ast.SliceExpression:
+        left, short := evalChain(node.Left, env)
+        if short || isError(left) {
+            return left, short
+        }
+        if node.Optional && left == NULL {
+            return NULL, true
+        }
+        return withPosition(evalSliceExpression(node, left, env), node.Token), false
+
+    case *ast.FunctionCall:
+        f, short := evalChain(node.Func, env)
+        if short || isError(f) {
+            return f, short
+        }
+        args, named, err := evalArguments(node.Args, env)
+        if err != nil {
+            return err, false
+        }
+        return withPosition(callFunction(f, args, named), node.Token), false
+    }
+
+    return Eval(node, env), false
+}
+
+func evalSliceExpression(se *ast.SliceExpression, left object.Object, env *object.Env) object.Object {
+    bounds := []object.Object{NULL, NULL, NULL}
+    for i, exp := range []ast.Expression{se.Start, se.End, se.Step} {
+        if exp == nil {
+            continue
+        }
+        bounds[i] = Eval(exp, env)
+        if isError(bounds[i]) {
+            return bounds[i]
+        }
+    }
+
+    switch left := left.(type) {
+    case *object.Array:
+        indices, err := sliceIndices(len(left.Elems), bounds[0], bounds[1], bounds[2])
+        if err != nil {
+            return err
+        }
+        elems := make([]object.Object, 0, len(indices))
+        for _, i := range indices {
+            elems = append(elems, left.Elems[i])
+        }
+        return &object.Array{Elems: elems}
+
+    case *object.String:
+        runes := []rune(left.Value)
+        indices, err := sliceIndices(len(runes), bounds[0], bounds[1], bounds[2])
+        if err != nil {
+            return err
+        }
+        out := make([]rune, 0, len(indices))
+        for _, i := range indices {
+            out = append(out, runes[i])
+        }
+        return &object.String{Value: string(out)}
+    }
+
+    return newErrorKind(typeError, "slice operator not supported: %s", left.Type())
+}
+
+// Pythonのスライスと同じ規則で、長さlengthの列から取り出す添字を順に返す.
+// start, end, stepはINTEGERまたは省略を表すNULL
+func sliceIndices(length int, start, end, step object.Object) ([]int, *object.Error) {
+    n := int64(length)
+    bound := func(obj object.Object, def int64) (int64, *object.Error) {
+        if obj == NULL {
+            return def, nil
+        }
+        i, ok := obj.(*object.Integer)
+        if !ok {
+            return 0, newErrorKind(typeError, "slice indices must be INTEGER, got %s", obj.Type())
+        }
+        return i.Value, nil
+    }
+
+    st, err := bound(step, 1)
+    if err != nil {
+        return nil, err
+    }
+    if st == 0 {
+        return nil, newErrorKind(valueError, "slice step cannot be zero")
+    }
+
+    // 負のstepでは末尾から先頭に向かって進むため、既定値と丸め方が変わる
+    lower, upper := int64(0), n
+    defStart, defEnd := int64(0), n
+    if st < 0 {
+        lower, upper = -1, n - 1
+        defStart, defEnd = n - 1, -1
+    }
+    clamp := func(i int64) int64 {
+        if i < 0 {
+            i += n
+        }
+        if i < lower {
+            return lower
+        }
+        if i > upper {
+            return upper
+        }
+        return i
+    }
+
+    s, err := bound(start, defStart)
+    if err != nil {
+        return nil, err
+    }
+    if start != NULL {
+        s = clamp(s)
+    }
+    e, err := bound(end, defEnd)
+    if err != nil {
+        return nil, err
+    }
+    if end != NULL {
+        e = clamp(e)
+    }
+
+    indices := []int{}
+    for i := s; (st > 0 && i < e) || (st < 0 && i > e); i += st {
+        indices = append(indices, int(i))
+        // stepが大きいとi += stがあふれるので、eに届く場合は足す前に止める
+        if (st > 0 && e - i <= st) || (st < 0 && e - i >= st) {
+            break
+        }
+    }
+    return indices, nil
 }
 
 func evalHashLiteral(hl *ast.HashLiteral, env *object.Env) object.Object {
     
-    pairs := map[object.HashKey]object.HashPair{}
+    h := object.NewHash()
 
-    for key, val := range hl.Pairs {
-        key_evaled := Eval(key, env)
+    for _, pair := range hl.Pairs {
+        key_evaled := Eval(pair.Key, env)
+        if isError(key_evaled) {
+            return key_evaled
+        }
 
-        hasha, ok := key_evaled.(object.Hashable)
+        hasha, ok := hashable(key_evaled)
         if !ok {
-            return newError("hash keys %s doesn't have Hashkey()", key_evaled.Type())
+            return newErrorKind(typeError, "hash keys %s doesn't have Hashkey()", key_evaled.Type())
         }
-        hk := hasha.HashKey()
-
-        hp := object.HashPair{Key: key_evaled, Value: Eval(val, env)}
-        pairs[hk] = hp
+        val := Eval(pair.Value, env)
+        if isError(val) {
+            return val
+        }
+        h.Set(hasha, val)
     }
 
-    h := &object.Hash{Pairs: pairs}
     return h
 }
 
+func evalSetLiteral(sl *ast.SetLiteral, env *object.Env) object.Object {
+    s := object.NewSet()
+
+    for _, e := range sl.Elems {
+        elem := Eval(e, env)
+        if isError(elem) {
+            return elem
+        }
+
+        hk, ok := hashable(elem)
+        if !ok {
+            return newErrorKind(typeError, "unusable as set element: %s", elem.Type())
+        }
+        s.Add(hk)
+    }
+
+    return s
+}
+
+// objがハッシュのキーに使えるならHashableとして返す.
+// 配列はHashKeyを持つが、関数などを含むとキーにできないので型アサーションだけでは判定できない
+func hashable(obj object.Object) (object.Hashable, bool) {
+    if !object.IsHashable(obj) {
+        return nil, false
+    }
+    return obj.(object.Hashable), true
+}
+
 func evalHashIndexExpression(left, index object.Object) object.Object {
     h := left.(*object.Hash)
 
-    key, ok := index.(object.Hashable)
+    key, ok := hashable(index)
     if !ok {
-        return newError("unusable as hash key: %s", index.Type())
+        return newErrorKind(typeError, "unusable as hash key: %s", index.Type())
     }
 
-    pair, ok := h.Pairs[key.HashKey()]
+    pair, ok := h.Get(key)
     if !ok {
         return NULL
     }
@@ -310,42 +1114,276 @@ func evalHashIndexExpression(left, index object.Object) object.Object {
     return pair.Value
 }
 
+func evalAssignExpression(ae *ast.AssignExpression, env *object.Env) object.Object {
+    switch target := ae.Target.(type) {
+    case *ast.Identifier:
+        scope := env.Resolve(target.Value)
+        if scope == nil {
+            return newErrorKind(nameError, "assignment to undeclared variable: %s", target.Value)
+        }
+        if scope.IsConst(target.Value) {
+            return newError("cannot assign to constant %s", target.Value)
+        }
+
+        val := Eval(ae.Value, env)
+        if isError(val) {
+            return val
+        }
+
+        if ae.Operator != "=" {
+            cur, _ := scope.Get(target.Value)
+            val = evalCompoundOperator(ae.Operator, cur, val)
+            if isError(val) {
+                return val
+            }
+        }
+
+        return scope.Set(target.Value, val)
+
+    case *ast.IndexExpression:
+        left := Eval(target.Left, env)
+        if isError(left) {
+            return left
+        }
+        index := Eval(target.Index, env)
+        if isError(index) {
+            return index
+        }
+        val := Eval(ae.Value, env)
+        if isError(val) {
+            return val
+        }
+
+        if ae.Operator != "=" {
+            cur := evalIndexExpression(left, index)
+            if isError(cur) {
+                return cur
+            }
+            val = evalCompoundOperator(ae.Operator, cur, val)
+            if isError(val) {
+                return val
+            }
+        }
+
+        return evalIndexAssignment(left, index, val)
+    }
+
+    return newError("cannot assign to %s", ae.Target.String())
+}
+
+// `+=`などの複合代入は、`=`を除いた演算子で計算した値を代入する
+func evalCompoundOperator(op string, cur, val object.Object) object.Object {
+    return evalInfixExpression(op[:len(op)-1], cur, val)
+}
+
+// objの中に、入れ子も含めてtargetそのものが入っているかどうか
+func containsObject(obj, target object.Object) bool {
+    if obj == target {
+        return true
+    }
+    switch obj := obj.(type) {
+    case *object.Array:
+        for _, elem := range obj.Elems {
+            if containsObject(elem, target) {
+                return true
+            }
+        }
+    case *object.Hash:
+        // キーは凍結したコピーなので、値だけを調べればよい
+        for _, pair := range obj.Pairs() {
+            if containsObject(pair.Value, target) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+func evalIndexAssignment(left, index, val object.Object) object.Object {
+    // 自分自身を含む配列やハッシュはInspectやEqualsが終わらなくなるので作らせない
+    if containsObject(val, left) {
+        return newErrorKind(valueError, "cannot store %s inside itself", left.Type())
+    }
+
+    switch left := left.(type) {
+    case *object.Array:
+        if left.Frozen {
+            return newErrorKind(typeError, "cannot assign to frozen %s", left.Type())
+        }
+        i, ok := index.(*object.Integer)
+        if !ok {
+            return newErrorKind(typeError, "array index must be INTEGER, got %s", index.Type())
+        }
+        idx, ok := normalizeIndex(i.Value, len(left.Elems))
+        if !ok {
+            return newErrorKind(indexError, "index out of range: %d (len %d)", i.Value, len(left.Elems))
+        }
+        left.Elems[idx] = val
+        return val
+
+    case *object.Hash:
+        if left.Frozen {
+            return newErrorKind(typeError, "cannot assign to frozen %s", left.Type())
+        }
+        key, ok := hashable(index)
+        if !ok {
+            return newErrorKind(typeError, "unusable as hash key: %s", index.Type())
+        }
+        left.Set(key, val)
+        return val
+    }
+
+    return newErrorKind(typeError, "index assignment not supported: %s", left.Type())
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
 
     if left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ {
         return evalArrayIndexExpression(left, index)
+    } else if left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ {
+        return evalStringIndexExpression(left, index)
+    } else if left.Type() == object.RANGE_OBJ && index.Type() == object.INTEGER_OBJ {
+        return evalRangeIndexExpression(left, index)
     } else if left.Type() == object.HASH_OBJ {
         return evalHashIndexExpression(left, index)
+    } else if err, ok := left.(*object.Error); ok {
+        // 値としてのエラーは e["message"] のようにハッシュと同じく読める
+        return evalHashIndexExpression(errorHash(err), index)
     }
 
-    return newError("index operator not supported: %s", left.Type())
+    return newErrorKind(typeError, "index operator not supported: %s", left.Type())
 }
 
 func applyFunction(fn object.Object, args []object.Object) object.Object {
+    return callFunction(fn, args, nil)
+}
+
+func callFunction(fn object.Object, args []object.Object, named []binding) object.Object {
 
     switch fn := fn.(type) {
     case *object.Function:
-        extendedEnv := extendFunctionEnv(fn, args)
+        extendedEnv, err := extendFunctionEnv(fn, args, named)
+        if err != nil {
+            return err
+        }
         evaled := Eval(fn.Body, extendedEnv)
         return unwrapReturnValue(evaled)
     case *object.Builtin:
+        if len(named) > 0 {
+            return newErrorKind(argumentError, "builtin function does not accept named arguments")
+        }
         return fn.Fn(args...)
     default:
-        return newError("not a function: %s", fn.Type())
+        return newErrorKind(typeError, "not a function: %s", fn.Type())
     }
 }
 
-func extendFunctionEnv(f *object.Function, args []object.Object) *object.Env {
+func extendFunctionEnv(f *object.Function, args []object.Object, named []binding) (*object.Env, *object.Error) {
     // f.Envに包まれた新しい環境envを生成
     env := object.NewEnclosedEnv(f.Env)
 
-    // 新しい環境envに引数の名前と値をsetする
-    for i, param := range f.Params {
-        env.Set(param.Value, args[i])
+    params := f.Params
+    var rest *ast.RestPattern
+    if n := len(params); n > 0 {
+        if rp, ok := params[n-1].(*ast.RestPattern); ok {
+            rest = rp
+            params = params[:n-1]
+        }
+    }
+
+    if rest == nil && len(args) > len(params) {
+        return nil, newErrorKind(argumentError, "wrong number of arguments. got=%d, want=%s", len(args), arity(params, rest))
+    }
+
+    namedArgs := map[string]object.Object{}
+    for _, na := range named {
+        if _, ok := namedArgs[na.name]; ok {
+            return nil, newErrorKind(argumentError, "argument %s given more than once", na.name)
+        }
+        namedArgs[na.name] = na.val
+    }
+
+    // 新しい環境envに引数を分解した名前と値を、引数の順にsetする.
+    // デフォルト値はそれより前の引数を参照できるよう、env上で評価する
+    for i, param := range params {
+        target, def := param, ast.Expression(nil)
+        if dp, ok := param.(*ast.DefaultPattern); ok {
+            target, def = dp.Target, dp.Default
+        }
+
+        name := ""
+        if id, ok := target.(*ast.Identifier); ok {
+            name = id.Value
+        }
+        namedVal, isNamed := namedArgs[name]
+        delete(namedArgs, name)
+
+        var val object.Object
+        switch {
+        case i < len(args):
+            if isNamed {
+                return nil, newErrorKind(argumentError, "argument %s given more than once", name)
+            }
+            val = args[i]
+        case isNamed:
+            val = namedVal
+        case def != nil:
+            val = Eval(def, env)
+            if isError(val) {
+                return nil, val.(*object.Error)
+            }
+        case len(named) > 0:
+            return nil, newErrorKind(argumentError, "missing argument for parameter %s", target.String())
+        default:
+            return nil, newErrorKind(argumentError, "wrong number of arguments. got=%d, want=%s", len(args), arity(params, rest))
+        }
+
+        bindings, err := destructure(target, val, nil)
+        if err != nil {
+            return nil, err
+        }
+        for _, b := range bindings {
+            env.Set(b.name, b.val)
+        }
+    }
+
+    for _, na := range named {
+        if _, ok := namedArgs[na.name]; ok {
+            return nil, newErrorKind(argumentError, "unknown named argument: %s", na.name)
+        }
+    }
+
+    // 余った引数は配列としてrestにまとめる
+    if rest != nil {
+        var remain []object.Object
+        if len(args) > len(params) {
+            remain = make([]object.Object, len(args) - len(params))
+            copy(remain, args[len(params):])
+        }
+        env.Set(rest.Name.Value, &object.Array{Elems: remain})
     }
 
     // f.Envに包まれた小さな環境envを返す
-    return env
+    return env, nil
+}
+
+// エラーメッセージ用に、受け取れる引数の数を "2", "1..2", "1+" の形で返す
+func arity(params []ast.Pattern, rest *ast.RestPattern) string {
+    required := 0
+    for _, param := range params {
+        if _, ok := param.(*ast.DefaultPattern); !ok {
+            required++
+        }
+    }
+
+    switch {
+    case rest != nil:
+        return fmt.Sprintf("%d+", required)
+    case required == len(params):
+        return fmt.Sprintf("%d", required)
+    default:
+        return fmt.Sprintf("%d..%d", required, len(params))
+    }
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -356,6 +1394,38 @@ func unwrapReturnValue(obj object.Object) object.Object {
     return obj
 }
 
+// 負の無限大方向へ丸める整数除算
+func floorDiv(a, b int64) int64 {
+    q := a / b
+    if a % b != 0 && (a < 0) != (b < 0) {
+        q--
+    }
+    return q
+}
+
+// int64に収まる値はInteger、収まらない値はBigIntegerとして返す
+func newIntegerObject(b *big.Int) object.Object {
+    if b.IsInt64() {
+        return &object.Integer{Value: b.Int64()}
+    }
+    return &object.BigInteger{Value: b}
+}
+
+func toBigInt(obj object.Object) *big.Int {
+    switch obj := obj.(type) {
+    case *object.Integer:
+        return big.NewInt(obj.Value)
+    case *object.BigInteger:
+        return obj.Value
+    }
+    return nil
+}
+
+func isInteger(obj object.Object) bool {
+    t := obj.Type()
+    return t == object.INTEGER_OBJ || t == object.BIGINT_OBJ
+}
+
 func nativeBoolToBooleanObject(b bool) object.Object {
     if b {
         return TRUE
@@ -378,13 +1448,45 @@ func isTruthly(cond object.Object) bool {
     }
 }
 
+// object.Error.Kindに入るエラーの種類
+const (
+    genericError = "Error"
+    typeError = "TypeError"
+    nameError = "NameError"
+    argumentError = "ArgumentError"
+    indexError = "IndexError"
+    zeroDivisionError = "ZeroDivisionError"
+    valueError = "ValueError"
+    matchError = "MatchError"
+)
+
 func newError(format string, a ...interface{}) *object.Error {
-    return &object.Error{Msg: fmt.Sprintf(format, a...)}
+    return newErrorKind(genericError, format, a...)
 }
 
-func isError(obj object.Object) bool {
-    if obj != nil {
-        return (obj.Type() == object.ERROR_OBJ)
+func newErrorKind(kind string, format string, a ...interface{}) *object.Error {
+    return &object.Error{Kind: kind, Msg: fmt.Sprintf(format, a...)}
+}
+
+// objがまだ位置を持たないエラーであれば、tokの位置で発生したものとして記録する.
+// 内側のnodeで発生したエラーは既に位置を持つので、最も内側の位置が残る
+func withPosition(obj object.Object, tok token.Token) object.Object {
+    if err, ok := obj.(*object.Error); ok && err.Line == 0 {
+        err.Line = tok.Line
+        err.Column = tok.Column
     }
-    return false
+    return obj
+}
+
+// objが評価を中断させて伝播するエラーかどうか. 値としてのエラーはfalse
+func isError(obj object.Object) bool {
+    err, ok := obj.(*object.Error)
+    return ok && !err.IsValue
+}
+
+// 伝播するエラーを値としてのエラーに変換する
+func errorValue(err *object.Error) *object.Error {
+    v := *err
+    v.IsValue = true
+    return &v
 }
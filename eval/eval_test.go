@@ -22,6 +22,17 @@ func TestEvalIntegerExpression(t *testing.T) {
         {"(2 + 3) * (4 + 5)", 45},
         {"2 *  -10", -20},
         {"(2 + 8)/ 5 - 10", -8},
+        {"7 % 3", 1},
+        {"-7 % 3", -1},
+        {"7 % -3", 1},
+        {"7 // 2", 3},
+        {"-7 // 2", -4},
+        {"7 // -2", -4},
+        {"-7 // -2", 3},
+        {"-7 / 2", -3},
+        {"2 + 7 % 4 * 2", 8},
+        {"(9223372036854775807 + 1) % 7", 1},
+        {"-(9223372036854775807 + 2) // 2", -4611686018427387905},
     }
 
     for _, test := range tests {
@@ -30,6 +41,66 @@ func TestEvalIntegerExpression(t *testing.T) {
     }
 }
 
+func TestBigIntegerPromotion(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"9223372036854775807 + 1", "9223372036854775808"},
+        {"-9223372036854775807 - 2", "-9223372036854775809"},
+        {"4294967296 * 4294967296", "18446744073709551616"},
+        {"-(-9223372036854775807 - 1)", "9223372036854775808"},
+        {"(-9223372036854775807 - 1) / -1", "9223372036854775808"},
+        {"18446744073709551614", "18446744073709551614"},
+        {"0xffff_ffff_ffff_ffff + 1", "18446744073709551616"},
+        {"9223372036854775807 * 9223372036854775807 / 9223372036854775807", "9223372036854775807"},
+        {`
+        let fact = fn(n) { if (n < 2) { return 1; } n * fact(n - 1) };
+        fact(25)`, "15511210043330985984000000"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+        if evaled == nil || evaled.Inspect() != test.expected {
+            t.Errorf("%s: %s expected, but got %+v", test.input, test.expected, evaled)
+        }
+    }
+}
+
+func TestBigIntegerNormalization(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    }{
+        {"9223372036854775807 + 1 - 1", 9223372036854775807},
+        {"(9223372036854775807 + 10) - (9223372036854775807 + 5)", 5},
+        {"-(9223372036854775807 + 1)", -9223372036854775807 - 1},
+        {"-9223372036854775808", -9223372036854775807 - 1},
+        {"18446744073709551614 - 18446744073709551613", 1},
+    }
+
+    for _, test := range tests {
+        testIntegerObject(t, testEval(test.input), test.expected)
+    }
+}
+
+func TestBigIntegerComparison(t *testing.T) {
+    tests := []struct {
+        input string
+        expected bool
+    }{
+        {"9223372036854775807 + 1 > 9223372036854775807", true},
+        {"9223372036854775807 + 1 < 1", false},
+        {"9223372036854775807 + 1 == 9223372036854775807 + 1", true},
+        {"9223372036854775807 + 1 != 9223372036854775807 + 2", true},
+        {`{9223372036854775807 + 1: true}[9223372036854775806 + 2]`, true},
+    }
+
+    for _, test := range tests {
+        testBooleanObject(t, testEval(test.input), test.expected)
+    }
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
     tests := []struct {
         input string
@@ -44,6 +115,30 @@ func TestEvalBooleanExpression(t *testing.T) {
         {`"tom" == "tom"`, true},
         {`"bom" == "rom"`, false},
         {`"bom" != "rom"`, true},
+        {"1 <= 1", true},
+        {"2 <= 1", false},
+        {"1 >= 2", false},
+        {"2 >= 2", true},
+        {`"apple" < "banana"`, true},
+        {`"apple" > "apple pie"`, false},
+        {`"b" >= "abc"`, true},
+        {`"abc" <= "abc"`, true},
+        {"true == true", true},
+        {"true == false", false},
+        {"(1 < 2) == true", true},
+        {"false != true", true},
+        {`1 == "1"`, false},
+        {`1 != "1"`, true},
+        {"true == 1", false},
+        {"if (false) { 1 } == if (false) { 2 }", true},
+        {"[1, 2, [3]] == [1, 2, [3]]", true},
+        {"[1, 2] == [1, 2, 3]", false},
+        {"[1, 2] != [2, 1]", true},
+        {`{"a": [1], "b": 2} == {"b": 2, "a": [1]}`, true},
+        {`{"a": 1} == {"a": 2}`, false},
+        {`{"a": 1} == {"b": 1}`, false},
+        {"let f = fn(x) { x }; f == f", true},
+        {"fn(x) { x } == fn(x) { x }", false},
     }
 
     for _, test := range tests {
@@ -71,6 +166,50 @@ func TestBangOperator(t *testing.T) {
     }
 }
 
+func TestLogicalOperators(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"true && true", true},
+        {"true && false", false},
+        {"false && true", false},
+        {"false || true", true},
+        {"false || false", false},
+        {"1 && 2", 2},
+        {"1 || 2", 1},
+        {"false || 3", 3},
+        {"if (false) { 1 } || 4", 4},
+        {"1 < 2 && 3 < 4", true},
+        {"1 > 2 || 3 == 3", true},
+        {"false && undefined_name", false},
+        {"true || undefined_name", true},
+        {"let f = fn() { 1 / 0 }; false && f()", false},
+        {"1 < 2 ? 10 : 20", 10},
+        {"1 > 2 ? 10 : 20", 20},
+        {"let x = 0; x == 1 ? 1 : x == 0 ? 2 : 3", 2},
+        {"let n = 5; n > 3 ? n * 2 : undefined_name", 10},
+        {`let h = {"a": 1}; h["b"] ?? 7`, 7},
+        {`let h = {"a": 1}; h["a"] ?? 7`, 1},
+        {"false ?? 1", false},
+        {"0 ?? undefined_name", 0},
+        {`let h = {"a": {"b": 2}}; h?.["a"]?.["b"]`, 2},
+        {`let h = {"a": {"b": 2}}; h["x"]?.["b"] ?? 9`, 9},
+        {`let h = {"a": 1}; h["x"]?.[undefined_name] ?? 4`, 4},
+        {"[1, 2]?.[5] ?? 6", 6},
+        {`let h = {}; h["a"]?.["b"]["c"] ?? 3`, 3},
+        {`let h = {}; h["a"]?.["b"][1:]["c"] ?? 5`, 5},
+        {`let h = {}; h["a"]?.["f"](undefined_name)[0] ?? 8`, 8},
+        {`let h = {"a": {"b": {"c": 1}}}; h["a"]?.["b"]["c"]`, 1},
+        {`let h = {"a": {}}; h?.["a"]["b"]?.["c"]["d"] ?? 2`, 2},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
 func TestIfElseExpression(t *testing.T) {
     tests := []struct {
         input string
@@ -122,6 +261,54 @@ func TestReturnStatements(t *testing.T) {
     }
 }
 
+func TestLoops(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"while (false) { 1 }", nil},
+        {"while (true) { break; }", nil},
+        {"fn() { while (true) { return 5; } }()", 5},
+        {"fn(xs) { for (x in xs) { if (x > 2) { return x; } } return -1; }([1, 2, 3, 4])", 3},
+        {"fn(xs) { for (x in xs) { if (x > 9) { return x; } } return -1; }([1, 2, 3, 4])", -1},
+        {`
+        fn() {
+            for (x in [1, 2, 3]) {
+                if (x == 1) { continue; }
+                if (x == 2) { break; }
+                return 100;
+            }
+            return 0;
+        }()`, 0},
+        {"fn(n) { for (i in n) { if (i * i > 50) { return i; } } }(100)", 8},
+        {`fn() { for (c in "héllo") { if (c == "é") { return 1; } } return 0; }()`, 1},
+        {`fn() { for (k in {"only": 1}) { return len(k); } }()`, 4},
+        {"for (x in []) { x }", nil},
+        {"let i = 0; while (i < 5) { i += 1 }; i", 5},
+        {"let s = 0; for (x in [1, 2]) { s += x }; s", 3},
+        {"for (x in [1, 2]) { let y = x; }", nil},
+        {`
+        fn() {
+            for (x in [1, 2]) {
+                for (y in [1, 2]) {
+                    if (y == 2) { break; }
+                    if (x == 2) { return x * 10 + y; }
+                }
+            }
+        }()`, 21},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+        i, ok := test.expected.(int)
+        if ok {
+            testIntegerObject(t, evaled, int64(i))
+        } else {
+            testNullObject(t, evaled)
+        }
+    }
+}
+
 func TestErrorHandling(t *testing.T) {
     tests := []struct {
         input string
@@ -165,6 +352,58 @@ func TestErrorHandling(t *testing.T) {
             "foo",
             "identifier not found: foo",
         },
+        {
+            "1 / 0",
+            "division by zero",
+        },
+        {
+            "true && 1 / 0",
+            "division by zero",
+        },
+        {
+            "for (x in [1, 0]) { 1 / x }",
+            "division by zero",
+        },
+        {
+            "for (x in true) { x }",
+            "BOOLEAN is not iterable",
+        },
+        {
+            "x = 1",
+            "assignment to undeclared variable: x",
+        },
+        {
+            "len += 1",
+            "assignment to undeclared variable: len",
+        },
+        {
+            `let a = 1; a += "s"`,
+            "type mismatch: INTEGER + STRING",
+        },
+        {
+            "let a = [1]; a[1] = 2",
+            "index out of range: 1 (len 1)",
+        },
+        {
+            `let a = [1]; a["0"] = 2`,
+            "array index must be INTEGER, got STRING",
+        },
+        {
+            `let s = "abc"; s[0] = "x"`,
+            "index assignment not supported: STRING",
+        },
+        {
+            "1 % 0",
+            "division by zero",
+        },
+        {
+            "1 // 0",
+            "division by zero",
+        },
+        {
+            "(9223372036854775807 + 1) / 0",
+            "division by zero",
+        },
         {
             `"Hello" - "World"`,
             "unknown operator: STRING - STRING",
@@ -206,6 +445,155 @@ func TestLetStatement(t *testing.T) {
     }
 }
 
+func TestAssignExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    }{
+        {"let a = 1; a = 2; a;", 2},
+        {"let a = 1; a = a + 1;", 2},
+        {"let a = 1; let b = 1; a = b = 3; a + b;", 6},
+        {"let a = 10; a += 5; a -= 3; a *= 2; a /= 4; a;", 6},
+        {"let a = 17; a %= 5; a;", 2},
+        {"let a = -7; a //= 2; a;", -4},
+        {"let i = 0; let s = 0; while (i < 5) { s += i; i += 1; } s;", 10},
+        {"let s = 0; for (x in [1, 2, 3]) { s += x; } s;", 6},
+        {`
+        let counter = fn() {
+            let c = 0;
+            fn() { c += 1; c }
+        };
+        let next = counter();
+        next(); next(); next();`, 3},
+        {"let a = 1; let f = fn() { let a = 5; a = 6; }; f(); a;", 1},
+        {"let a = [1, 2, 3]; a[1] = 5; a[1];", 5},
+        {"let a = [1, 2, 3]; a[0] += 10; a[0] + a[2];", 14},
+        {`let h = {}; h["x"] = 1; h["x"] += 2; h["x"];`, 3},
+        {`let h = {"a": [1, 2]}; h["a"][1] = 7; h["a"][1];`, 7},
+        {"let a = [1, 2]; let b = push(a, 3); b[0] = 9; a[0];", 1},
+        {"let a = [1, 2, 3]; let b = rest(a); b[0] = 9; a[1];", 2},
+    }
+
+    for _, test := range tests {
+        testIntegerObject(t, testEval(test.input), test.expected)
+    }
+}
+
+func TestCyclicAssignment(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let a = [1]; a[0] = a", "cannot store ARRAY inside itself"},
+        {"let a = [1]; let b = [[a]]; a[0] = b", "cannot store ARRAY inside itself"},
+        {`let h = {}; h["self"] = h`, "cannot store HASH inside itself"},
+        {`let h = {}; h["x"] = [1, {"y": h}]`, "cannot store HASH inside itself"},
+        {"let a = [1]; try { a[0] = a } catch (e) { 0 }; a", "[1]"},
+        {"let a = [1]; let b = [a, a]; b[0] = a; b", "[[1], [1]]"},
+        {"let a = [[1]]; a == a", "true"},
+        {`let h = {"k": 1}; h["k"] = [h["k"]]; h`, "{k: [1]}"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        if errObj, ok := evaled.(*object.Error); ok {
+            if errObj.Msg != test.expected || errObj.Kind != "ValueError" {
+                t.Errorf("%s: expected %s, but got %s", test.input, test.expected, errObj.Inspect())
+            }
+        } else if evaled.Inspect() != test.expected {
+            t.Errorf("%s: expected %s, but got %s", test.input, test.expected, evaled.Inspect())
+        }
+    }
+}
+
+func TestConstStatement(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"const a = 5; a;", 5},
+        {"const a = [1]; a[0] = 2; a[0];", 2},
+        {"const a = 1; let f = fn() { let a = 2; a }; f();", 2},
+        {"const a = 1; let f = fn() { const a = 2; a }; f() + a;", 3},
+        {"let a = 1; const a = 2; a;", 2},
+        {"const a = 1; a = 2;", "cannot assign to constant a"},
+        {"const a = 1; a += 2;", "cannot assign to constant a"},
+        {"const a = 1; let f = fn() { a = 2; }; f();", "cannot assign to constant a"},
+        {"const a = 1; let a = 2;", "cannot redeclare constant a"},
+        {"const a = 1; const a = 2;", "cannot redeclare constant a"},
+        {"const a = 1; if (true) { let a = 2; }", "cannot redeclare constant a"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestDestructuring(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"let [a, b] = [1, 2]; a + b;", 3},
+        {"let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + a;", 21},
+        {"let [a, ...rest] = [1]; len(rest);", 0},
+        {"let [a, [b, c]] = [1, [2, 3]]; a + b + c;", 6},
+        {"let [_, b, _] = [1, 2, 3]; b;", 2},
+        {`let {name, age} = {"name": "monkey", "age": 3}; age;`, 3},
+        {`let {pos: [x, y]} = {"pos": [4, 5]}; x * y;`, 20},
+        {`let {a, ...others} = {"a": 1, "b": 2, "c": 3}; others["b"] + others["c"];`, 5},
+        {`let {a, ...others} = {"a": 1, "b": 2}; others["a"];`, nil},
+        {"let a = [1, 2, 3]; let [x, ...r] = a; r[0] = 9; a[1];", 2},
+        {`let f = fn([a, b], {c}) { a + b + c }; f([1, 2], {"c": 3});`, 6},
+        {"let pairs = [[1, 2], [3, 4]]; let s = 0; for (p in pairs) { let [x, y] = p; s += x * y; } s;", 14},
+        {"const [a, b] = [1, 2]; a = 3;", "cannot assign to constant a"},
+        {"const a = 1; let [a, b] = [1, 2];", "cannot redeclare constant a"},
+        {"let [a, b] = [1];", "array pattern [a, b] expects 2 elements, got 1"},
+        {"let [a, b] = [1, 2, 3];", "array pattern [a, b] expects 2 elements, got 3"},
+        {"let [a, b, ...r] = [1];", "array pattern [a, b, ...r] expects at least 2 elements, got 1"},
+        {"let [a] = 1;", "cannot destructure INTEGER with array pattern [a]"},
+        {`let {x} = {"y": 1};`, `hash pattern {x} requires key "x"`},
+        {"let {x} = [1];", "cannot destructure ARRAY with hash pattern {x}"},
+        {"let f = fn([a]) { a }; f(1);", "cannot destructure INTEGER with array pattern [a]"},
+        {"let a = 1; let [a, b] = [5]; a;", "array pattern [a, b] expects 2 elements, got 1"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestDestructuringErrorKinds(t *testing.T) {
+    tests := []struct {
+        input string
+        kind string
+    }{
+        {"let [a, b] = [1];", "ValueError"},
+        {"let [a, b, ...r] = [1];", "ValueError"},
+        {`let {x} = {"y": 1};`, "ValueError"},
+        {"let [a] = 1;", "TypeError"},
+        {"let {x} = [1];", "TypeError"},
+        {"let f = fn([a]) { a }; f(1);", "TypeError"},
+        {`try { let [a] = "s"; } catch (e) { throw e }`, "TypeError"},
+    }
+
+    for _, test := range tests {
+        errObj, ok := testEval(test.input).(*object.Error)
+        if !ok {
+            t.Errorf("%s: no error object returned", test.input)
+            continue
+        }
+        if errObj.Kind != test.kind {
+            t.Errorf("%s: expected %s, but got %s", test.input, test.kind, errObj.Inspect())
+        }
+    }
+}
+
 func TestFunctionObject(t *testing.T) {
     input := "fn(x) { x + 2; };"
 
@@ -219,7 +607,7 @@ func TestFunctionObject(t *testing.T) {
         t.Fatalf("length of Params is incorrect")
     }
 
-    if fn.Params[0].Value != "x" {
+    if fn.Params[0].String() != "x" {
         t.Fatalf("incorrect params value")
     }
 
@@ -250,6 +638,171 @@ func TestFunctionApplication(t *testing.T) {
     }
 }
 
+func TestFunctionParameters(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"let f = fn(x, y = 10) { x + y }; f(1);", 11},
+        {"let f = fn(x, y = 10) { x + y }; f(1, 2);", 3},
+        {"let f = fn(x, y = x * 2) { x + y }; f(3);", 9},
+        {"let n = 0; let f = fn(x = n += 1) { x }; f(); f(); n;", 2},
+        {"let f = fn(first, ...others) { len(others) * 10 + first }; f(1, 2, 3);", 21},
+        {"let f = fn(first, ...others) { len(others) }; f(1);", 0},
+        {"let f = fn(x, y) { x - y }; f(y: 1, x: 10);", 9},
+        {"let f = fn(x, y = 2, z = 3) { x * 100 + y * 10 + z }; f(1, z: 9);", 129},
+        {"let f = fn(a, b) { a }; f(1);", "wrong number of arguments. got=1, want=2"},
+        {"let f = fn(a) { a }; f(1, 2);", "wrong number of arguments. got=2, want=1"},
+        {"let f = fn(a, b = 1) { a }; f();", "wrong number of arguments. got=0, want=1..2"},
+        {"let f = fn(a, ...r) { a }; f();", "wrong number of arguments. got=0, want=1+"},
+        {"let f = fn(a, b) { a }; f(1, a: 2);", "argument a given more than once"},
+        {"let f = fn(a, b) { a }; f(a: 1, a: 2);", "argument a given more than once"},
+        {"let f = fn(a) { a }; f(a: 1, c: 2);", "unknown named argument: c"},
+        {"let f = fn(a, b) { a }; f(b: 1);", "missing argument for parameter a"},
+        {`len(s: "abc");`, "builtin function does not accept named arguments"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestMatchExpression(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"match (2) { 1 => 10, 2 => 20, _ => 30 }", 20},
+        {"match (5) { 1 => 10, _ => 30 }", 30},
+        {"match (-1) { -1 => 1, _ => 0 }", 1},
+        {`match ("b") { "a" => 1, "b" => 2 }`, 2},
+        {"match (1 < 2) { false => 0, true => 1 }", 1},
+        {"match (7) { n => n * 2 }", 14},
+        {"match ([1, 2]) { [] => 0, [x] => x, [x, y] => x + y }", 3},
+        {"match ([1, 2, 3]) { [1, ...r] => len(r), _ => 0 }", 2},
+        {`match ({"kind": "sq", "side": 3}) { {kind: "circle"} => 0, {kind: "sq", side} => side * side }`, 9},
+        {"match (5) { n if n < 0 => -1, n if n > 0 => 1, _ => 0 }", 1},
+        {"let n = 1; match (2) { n => n }; n;", 1},
+        {"let f = fn(x) { match (x) { 0 => 1, n => n * f(n - 1) } }; f(5);", 120},
+        {"match (3) { 1 => 10, 2 => 20 }", "no match arm for value 3"},
+        {"match ([1]) { [a, b] => a }", "no match arm for value [1]"},
+        {"match (1) { n if x => n }", "identifier not found: x"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestTryCatch(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {`try { throw "oops" } catch (e) { e["message"] }`, "oops"},
+        {`try { 1 + true } catch (e) { e["kind"] }`, "TypeError"},
+        {`try { x } catch (e) { e["kind"] + ": " + e["message"] }`, "NameError: identifier not found: x"},
+        {`try { 1 // 0 } catch (e) { e["kind"] }`, "ZeroDivisionError"},
+        {`try { len(1) } catch (e) { e["message"] }`, "argument to `len` not supported, got INTEGER"},
+        {`try { [1][5] = 0 } catch ({kind}) { kind }`, "IndexError"},
+        {`try { 5 } catch (e) { 0 }`, 5},
+        {`try { throw 42 } catch (e) { e["value"] + 1 }`, 43},
+        {"let x = 0;\nlet y = 1;\n  x + true;", "type mismatch: INTEGER + BOOLEAN"},
+        {"try {\n  let y = 1;\n  y + true;\n} catch (e) { e[\"line\"] * 10 + e[\"column\"] }", 35},
+        {"let f = fn() {\n  throw \"bad\"\n};\ntry { f() } catch (e) { e[\"line\"] * 10 + e[\"column\"] }", 23},
+        {`let log = []; try { push(log, 1); throw "x" } catch (e) { log = push(log, 2) } finally { log = push(log, 3) }; len(log);`, 2},
+        {`let log = 0; try { throw "x" } catch (e) { log += 1 } finally { log += 10 }; log;`, 11},
+        {`let log = 0; try { 1 } finally { log += 10 }; log;`, 10},
+        {`let f = fn() { try { return 1 } finally { return 2 } }; f();`, 2},
+        {`try { try { throw "inner" } finally { 0 } } catch (e) { e["message"] }`, "inner"},
+        {`try { try { 1 + true } catch (e) { throw e } } catch (e) { e["kind"] }`, "TypeError"},
+        {`try { throw {"message": "custom", "kind": "MyError"} } catch (e) { e["kind"] }`, "MyError"},
+        {`throw "uncaught";`, "uncaught"},
+        {`try { throw "u" } catch (e) { e["kind"] }`, "Error"},
+        {`try { throw "a" } catch (e) { throw "b" }`, "b"},
+        {`let i = 0; while (true) { try { i += 1; if (i > 2) { break } } catch (e) { 0 } }; i;`, 3},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestErrorValues(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {`let e = error("bad input"); e["message"];`, "bad input"},
+        {`error("bad", "ValueError")["kind"];`, "ValueError"},
+        {`error("bad")["kind"];`, "Error"},
+        {`is_error(error("bad"));`, true},
+        {`is_error(1);`, false},
+        {`let f = fn(x) { if (x < 0) { return error("negative") } x * 2 }; let r = f(-1); 1; is_error(r);`, true},
+        {`let f = fn(x) { if (x < 0) { return error("negative") } x * 2 }; let r = f(3); is_error(r);`, false},
+        {`let errs = [error("a"), error("b")]; errs[1]["message"];`, "b"},
+        {`try { 1 + true } catch (e) { is_error(e) }`, true},
+        {`let {message} = error("m"); message;`, "m"},
+        {`try { throw error("v", "ValueError") } catch (e) { e["kind"] + ": " + e["message"] }`, "ValueError: v"},
+        {`let e = error("v"); throw e;`, "v"},
+        {`let e = try { x } catch (e) { e }; is_error(e) && e["kind"] == "NameError";`, true},
+        {`error(1);`, "argument to `error` must be STRING, got INTEGER"},
+        {`try { error(1) } catch (e) { e["kind"] }`, "TypeError"},
+        {`error("a")["nope"];`, nil},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestErrorPosition(t *testing.T) {
+    tests := []struct {
+        input string
+        line int
+        column int
+    }{
+        {"let a = 1;\nlet b = fn(x) {\n    x[0]\n};\nb(a);", 3, 6},
+        {"let a = 1;\n  {[fn(){}]: 1}", 2, 3},
+        {"let a = 1;\nlet s = {1, {}};", 2, 9},
+    }
+
+    for _, test := range tests {
+        err, ok := testEval(test.input).(*object.Error)
+        if !ok {
+            t.Fatalf("%s: no error object returned", test.input)
+        }
+        if err.Kind != "TypeError" || err.Line != test.line || err.Column != test.column {
+            t.Errorf("%q: expected TypeError at %d:%d, but got %s at %d:%d", test.input, test.line, test.column, err.Kind, err.Line, err.Column)
+        }
+    }
+}
+
+func TestPipeline(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    }{
+        {"[1, 2] |> push(3) |> rest |> len", 2},
+        {"let double = fn(x) { x * 2 }; 5 |> double |> double", 20},
+        {"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+        {"let f = fn(x, y = 1, z = 0) { x * y + z }; 4 |> f(z: 2)", 6},
+        {"let x = 3 |> fn(v) { v + 1 }; x", 4},
+    }
+
+    for _, test := range tests {
+        testIntegerObject(t, testEval(test.input), test.expected)
+    }
+}
+
 func TestStringLiteral(t *testing.T) {
     test := `"howdy? toasa."`
     evaled := testEval(test)
@@ -286,6 +839,7 @@ func TestBuiltinFunctions(t *testing.T) {
         {`len("")`, 0},
         {`len("five")`, 4},
         {`len("hello toasa")`, 11},
+        {`len("こんにちは")`, 5},
         {`len(1)`, "argument to `len` not supported, got INTEGER"},
         {`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
     }
@@ -309,6 +863,222 @@ func TestBuiltinFunctions(t *testing.T) {
     }
 }
 
+func TestHigherOrderBuiltins(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+        {"map(1..3, fn(x) { x * x })", "[1, 4, 9]"},
+        {`map(["a", "bc"], len)`, "[1, 2]"},
+        {"filter(1..10, fn(x) { x % 3 == 0 })", "[3, 6, 9]"},
+        {"reduce([1, 2, 3, 4], fn(acc, x) { acc + x })", "10"},
+        {"reduce([], fn(acc, x) { acc + x }, 100)", "100"},
+        {`reduce(["a", "b"], fn(acc, x) { acc + x }, ">")`, ">ab"},
+        {"sort([3, 1, 2])", "[1, 2, 3]"},
+        {`sort(["b", "c", "a"])`, "[a, b, c]"},
+        {"sort([3, 1, 2], fn(a, b) { a > b })", "[3, 2, 1]"},
+        {"sort([3, 1, 2], fn(a, b) { b - a })", "[3, 2, 1]"},
+        {"let a = [3, 1]; sort(a); a", "[3, 1]"},
+        {"map(sort([[2, 1], [1, 2], [2, 0], [1, 1]], fn(a, b) { a[0] < b[0] }), fn(p) { p[1] })", "[2, 1, 1, 0]"},
+        {"zip([1, 2, 3], [4, 5])", "[[1, 4], [2, 5]]"},
+        {`zip(0..<3, "ab", [true, false, true])`, "[[0, a, true], [1, b, false]]"},
+        {"range(3)", "0..<3"},
+        {"array(range(2, 5))", "[2, 3, 4]"},
+        {"array(range(0, 10, 3))", "[0, 3, 6, 9]"},
+        {"array(range(5, 0, -2))", "[5, 3, 1]"},
+        {"len(range(0, 10, 3))", "4"},
+        {"range(10, 0, -3)[-1]", "1"},
+        {"range(0, 4, 2) == range(0, 3, 2)", "true"},
+        {"map([1, 2], fn(x) { x / 0 })", "division by zero"},
+        {"map([1], 1)", "2nd argument to `map` must be FUNCTION, got INTEGER"},
+        {"reduce([], fn(a, b) { a })", "reduce of empty sequence with no initial value"},
+        {`sort([1, "a"])`, "cannot compare STRING and INTEGER"},
+        {`sort([1, 2], fn(a, b) { "x" })`, "comparator must return BOOLEAN or INTEGER, got STRING"},
+        {"range(0, 5, 0)", "range step cannot be zero"},
+        {"filter(true, fn(x) { x })", "BOOLEAN is not iterable"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestStringBuiltins(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`split("a,b,,c", ",")`, "[a, b, , c]"},
+        {`split("  a  b c ")`, "[a, b, c]"},
+        {`split("日本語", "")`, "[日, 本, 語]"},
+        {`join(["a", "b", "c"], "-")`, "a-b-c"},
+        {`join(["a", "b"])`, "ab"},
+        {`trim("  hi 
+")`, "hi"},
+        {`trim("xxhixx", "x")`, "hi"},
+        {`trim_start("  hi  ") + "|"`, "hi  |"},
+        {`trim_end("  hi  ") + "|"`, "  hi|"},
+        {`upper("ábc")`, "ÁBC"},
+        {`lower("ÀB")`, "àb"},
+        {`contains("monkey", "key")`, "true"},
+        {`starts_with("monkey", "mon")`, "true"},
+        {`ends_with("monkey", "mon")`, "false"},
+        {`index_of("こんにちは", "に")`, "2"},
+        {`index_of("abc", "z")`, "-1"},
+        {`replace("a-b-c", "-", "+")`, "a+b+c"},
+        {`replace("a-b-c", "-", "+", 1)`, "a+b-c"},
+        {`repeat("ab", 3)`, "ababab"},
+        {`pad_start("7", 3, "0")`, "007"},
+        {`pad_end("日本", 4, "・")`, "日本・・"},
+        {`pad_start("long", 2)`, "long"},
+        {`ord("あ")`, "12354"},
+        {`chr(12354)`, "あ"},
+        {`format("%d items", 3)`, "3 items"},
+        {`format("%s=%v, %x %o %b 100%%", "k", [1, "a"], 255, 8, 5)`, "k=[1, a], ff 10 101 100%"},
+        {`format("[%5d|%-4s|%03d]", 42, "ab", 7)`, "[   42|ab  |007]"},
+        {`format("%d", 9223372036854775807 + 1)`, "9223372036854775808"},
+        {`format("%s", true)`, "true"},
+        {`upper(1)`, "argument to `upper` must be STRING, got INTEGER"},
+        {`join([1, 2], ",")`, "argument to `join` must be STRING, got INTEGER"},
+        {`trim_end(1)`, "argument to `trim_end` must be STRING, got INTEGER"},
+        {`repeat("a", -1)`, "negative repeat count: -1"},
+        {`repeat("ab", 9223372036854775807)`, "result of `repeat` is too long"},
+        {`repeat("", 9223372036854775807)`, ""},
+        {`pad_start("a", 9223372036854775807, "é")`, "result of `pad_start` is too long"},
+        {`pad_end("a", 9223372036854775807)`, "result of `pad_end` is too long"},
+        {`pad_start("a", -9223372036854775807 - 1)`, "a"},
+        {`ord("ab")`, "argument to `ord` must be a single character, got \"ab\""},
+        {`chr(-1)`, "invalid code point: -1"},
+        {`pad_start("a", 3, "ab")`, "fill of `pad_start` must be a single character, got \"ab\""},
+        {`format("%d", "x")`, "format: %d expects INTEGER, got STRING"},
+        {`format("%d %d", 1)`, "format: missing argument for %d"},
+        {`format("%d", 1, 2)`, "format: 1 unused arguments"},
+        {`format("%z", 1)`, "format: unknown verb %z"},
+        {`format("50%")`, "format: incomplete verb at end of \"50%\""},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestHashBuiltins(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`keys({"b": 1, "a": 2, 3: 0, 1: 0, true: 0, false: 0})`, "[b, a, 3, 1, true, false]"},
+        {`values({"b": 1, "a": 2})`, "[1, 2]"},
+        {`entries({"b": 1, "a": 2})`, "[[b, 1], [a, 2]]"},
+        {`len({"a": 1, "b": 2})`, "2"},
+        {`has({"a": if (false) { 1 }}, "a")`, "true"},
+        {`has({"a": 1}, "b")`, "false"},
+        {`let h = {"a": 1, "b": 2}; let d = delete(h, "a"); [keys(d), len(h)]`, "[[b], 2]"},
+        {`delete({"a": 1}, "z")["a"]`, "1"},
+        {`let a = {"x": 1, "y": 2}; let m = merge(a, {"y": 3, "z": 4}); [values(m), len(a)]`, "[[1, 3, 4], 2]"},
+        {`[k for k in {"b": 1, "a": 2, "c": 3}]`, "[b, a, c]"},
+        {`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+        {`let h = {"x": 1, "y": 2}; h["x"] = 3; h["z"] = 4; h`, "{x: 3, y: 2, z: 4}"},
+        {`delete({"x": 1, "y": 2, "z": 3}, "y")`, "{x: 1, z: 3}"},
+        {`merge({"x": 1, "y": 2}, {"z": 3, "x": 4})`, "{x: 4, y: 2, z: 3}"},
+        {`let {x, ...r} = {"c": 1, "x": 2, "a": 3}; r`, "{c: 1, a: 3}"},
+        {`{x: x * x for x in [3, 1, 2]}`, "{3: 9, 1: 1, 2: 4}"},
+        {`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, "true"},
+        {`keys({})`, "[]"},
+        {`keys([1])`, "argument to `keys` must be HASH, got ARRAY"},
+        {`has({}, {})`, "unusable as hash key: HASH"},
+        {`merge({}, 1)`, "argument to `merge` must be HASH, got INTEGER"},
+        {`has({})`, "wrong number of arguments. got=1, want=2"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestCompositeHashKeys(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`let grid = {[0, 0]: "a", [0, 1]: "b"}; grid[[0, 1]]`, "b"},
+        {`let grid = {}; grid[[1, 2]] = 3; grid[[1, 2]] = 4; [len(grid), grid[[1, 2]]]`, "[1, 4]"},
+        {`{[1, [2, 3]]: 1}[[1, [2, 3]]]`, "1"},
+        {`{[1, 2]: 1}[[2, 1]]`, "null"},
+        {`{[]: 1}[[]]`, "1"},
+        {`has({[1, "a"]: true}, [1, "a"])`, "true"},
+        {`let k = [1, 2]; let h = {k: "x"}; k[0] = 9; [h[[1, 2]], h[[9, 2]], keys(h)]`, "[x, null, [[1, 2]]]"},
+        {`let k = [1]; let h = {}; h[k] = 1; k[0] = 2; h[k] = 2; h`, "{[1]: 1, [2]: 2}"},
+        {`{freeze({"x": 1, "y": 2}): "p"}[freeze({"y": 2, "x": 1})]`, "p"},
+        {`{[freeze({"a": [1]})]: 1}[[freeze({"a": [1]})]]`, "1"},
+        {`let f = freeze([1, [2]]); f[0] = 3`, "cannot assign to frozen ARRAY"},
+        {`let f = freeze([1, [2]]); f[1][0] = 3`, "cannot assign to frozen ARRAY"},
+        {`let f = freeze({"a": 1}); f["b"] = 2`, "cannot assign to frozen HASH"},
+        {`let a = [1]; let f = freeze(a); a[0] = 2; [a, f]`, "[[2], [1]]"},
+        {`freeze([1, 2]) == [1, 2]`, "true"},
+        {`freeze(1)`, "1"},
+        {`{{"a": 1}: 1}`, "hash keys HASH doesn't have Hashkey()"},
+        {`{[1, {"a": 1}]: 1}`, "hash keys ARRAY doesn't have Hashkey()"},
+        {`{[fn(x) { x }]: 1}`, "hash keys ARRAY doesn't have Hashkey()"},
+        {`{}[[{}]]`, "unusable as hash key: ARRAY"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestSets(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {`{3, 1, 2, 1}`, "{3, 1, 2}"},
+        {`{[0, 1], [0, 1], "a"}`, "{[0, 1], a}"},
+        {`set()`, "set()"},
+        {`set([2, 1, 2])`, "{2, 1}"},
+        {`set("abca")`, "{a, b, c}"},
+        {`set({"x": 1, "y": 2})`, "{x, y}"},
+        {`len({1, 2, 3})`, "3"},
+        {`[has({1, 2}, 2), has({1, 2}, 3), has({[1]}, [1])]`, "[true, false, true]"},
+        {`let s = {1, 2}; let t = add(s, 3); [s, t, add(t, 1)]`, "[{1, 2}, {1, 2, 3}, {1, 2, 3}]"},
+        {`let s = {1, 2, 3}; [remove(s, 2), remove(s, 4), s]`, "[{1, 3}, {1, 2, 3}, {1, 2, 3}]"},
+        {`remove({1}, 1)`, "set()"},
+        {`union({1, 2}, {2, 3}, {4})`, "{1, 2, 3, 4}"},
+        {`intersection({3, 1, 2}, {2, 3, 4}, {3, 2})`, "{3, 2}"},
+        {`difference({1, 2, 3, 4}, {2}, {4, 5})`, "{1, 3}"},
+        {`{1, 2} == {2, 1}`, "true"},
+        {`{1, 2} == {1}`, "false"},
+        {`let sum = 0; for (x in {1, 2, 3}) { sum += x; }; sum`, "6"},
+        {`[x * 10 for x in {3, 1}]`, "[30, 10]"},
+        {`map({1, 2}, fn(x) { x + 1 })`, "[2, 3]"},
+        {`array({"b", "a"})`, "[b, a]"},
+        {`let k = [1]; let s = {k}; k[0] = 2; [s, has(s, [1])]`, "[{[1]}, true]"},
+        {`{1, {"a": 1}}`, "unusable as set element: HASH"},
+        {`add({1}, {})`, "unusable as set element: HASH"},
+        {`set([fn(x) { x }])`, "unusable as set element: FUNCTION"},
+        {`union({1}, [2])`, "argument to `union` must be SET, got ARRAY"},
+        {`intersection()`, "wrong number of arguments. got=0, want=1+"},
+        {`set(1, 2)`, "wrong number of arguments. got=2, want=0..1"},
+        {`has([1], 1)`, "argument to `has` must be HASH, got ARRAY"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
 func TestArrayLiterals(t *testing.T) {
     input := "[1, 2 * 2, 3 + 3]"
     evaled := testEval(input)
@@ -361,8 +1131,20 @@ func TestArrayIndexExpressions(t *testing.T) {
         },
         {
             "[1, 2, 3][-1]",
+            3,
+        },
+        {
+            "[1, 2, 3][-3]",
+            1,
+        },
+        {
+            "[1, 2, 3][-4]",
             nil,
         },
+        {
+            "let a = [1, 2, 3]; a[-1] = 9; a[2]",
+            9,
+        },
     }
 
     for _, test := range tests {
@@ -376,6 +1158,137 @@ func TestArrayIndexExpressions(t *testing.T) {
     }
 }
 
+func TestStringIndexAndSlice(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {`"hello"[1]`, "e"},
+        {`"hello"[-1]`, "o"},
+        {`"こんにちは"[2]`, "に"},
+        {`"hello"[5]`, nil},
+        {`"hello"[1:3]`, "el"},
+        {`"hello"[:2]`, "he"},
+        {`"hello"[3:]`, "lo"},
+        {`"hello"[::-1]`, "olleh"},
+        {`"abc"[1::9223372036854775807]`, "b"},
+        {`"abc"[::-9223372036854775807]`, "c"},
+        {`"こんにちは"[1:-1]`, "んにち"},
+        {`"hello"[10:]`, ""},
+        {`"hello"[::0]`, "slice step cannot be zero"},
+        {`"hello"["a":]`, "slice indices must be INTEGER, got STRING"},
+        {`1[0:1]`, "slice operator not supported: INTEGER"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestArraySlice(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"[1, 2, 3, 4, 5][1:3]", "[2, 3]"},
+        {"[1, 2, 3, 4, 5][:-2]", "[1, 2, 3]"},
+        {"[1, 2, 3, 4, 5][-2:]", "[4, 5]"},
+        {"[1, 2, 3, 4, 5][::2]", "[1, 3, 5]"},
+        {"[1, 2, 3, 4, 5][::-2]", "[5, 3, 1]"},
+        {"[1, 2, 3, 4, 5][3:0:-1]", "[4, 3, 2]"},
+        {"[1, 2, 3, 4, 5][-100:100]", "[1, 2, 3, 4, 5]"},
+        {"[1, 2, 3][2:1]", "[]"},
+        {"[1, 2, 3][2:3:9223372036854775807]", "[3]"},
+        {"[1, 2, 3][::9223372036854775807]", "[1]"},
+        {"[1, 2, 3][::-9223372036854775807 - 1]", "[3]"},
+        {"[1, 2, 3][0:3:2]", "[1, 3]"},
+        {"[1, 2, 3][:]", "[1, 2, 3]"},
+        {"let a = [1, 2, 3]; let b = a[:]; b[0] = 9; a", "[1, 2, 3]"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+        if evaled.Inspect() != test.expected {
+            t.Errorf("%s: expected %s, but got %s", test.input, test.expected, evaled.Inspect())
+        }
+    }
+}
+
+func TestRangeExpressions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected interface{}
+    }{
+        {"1..5", "1..5"},
+        {"0..<3", "0..<3"},
+        {"len(1..10)", 10},
+        {"len(1..<10)", 9},
+        {"len(5..1)", 0},
+        {"len(0..9000000000000000000)", 9000000000000000001},
+        {"len(-9223372036854775807..<0)", 9223372036854775807},
+        {"len(-5..9223372036854775806)", "range length overflows int64: -5..9223372036854775806"},
+        {"len(-9223372036854775807..9223372036854775806)", "range length overflows int64: -9223372036854775807..9223372036854775806"},
+        {"(-5..9223372036854775806)[-1]", 9223372036854775806},
+        {"(-5..9223372036854775806)[9223372036854775807]", 9223372036854775802},
+        {"(-9223372036854775807..9223372036854775806)[-9223372036854775807 - 1]", -1},
+        {"let s = 0; for (i in -5..9223372036854775806) { if (i == 0) { break } s += i }; s", -15},
+        {"let s = 0; for (i in range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807)) { s += 1 }; s", 3},
+        {"(1..10)[0]", 1},
+        {"(1..10)[-1]", 10},
+        {"(1..<10)[-1]", 9},
+        {"(1..10)[10]", nil},
+        {"(-5..5)[5]", 0},
+        {"array(1..5)", "[1, 2, 3, 4, 5]"},
+        {"array(3..<3)", "[]"},
+        {`array("abc")`, "[a, b, c]"},
+        {"let s = 0; for (i in 1..100) { s += i }; s", 5050},
+        {"let s = 0; for (i in 0..1000000000000) { if (i == 3) { break } s += i }; s", 3},
+        {"1..3 == 1..<4", true},
+        {"1..3 == 1..4", false},
+        {"5..1 == 7..<2", true},
+        {`1.."a"`, "range bounds must be INTEGER, got INTEGER .. STRING"},
+        {"1..9223372036854775807", "range end overflows: 9223372036854775807"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
+func TestComprehensions(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"[x * 2 for x in [1, -2, 3] if x > 0]", "[2, 6]"},
+        {"[x for x in 5]", "[0, 1, 2, 3, 4]"},
+        {"[i * i for i in 1..4]", "[1, 4, 9, 16]"},
+        {`[c for c in "abc"]`, "[a, b, c]"},
+        {"[i * 10 + x for i, x in [7, 8]]", "[7, 18]"},
+        {"[a * b for [a, b] in [[1, 2], [3, 4]]]", "[2, 12]"},
+        {"let x = 100; [x for x in [1]]; x", "100"},
+        {"let n = 2; [x * n for x in [1, 2] if x != n]", "[2]"},
+        {`let h = {"a": 1}; [k for k in h]`, "[a]"},
+        {`let h = {"a": 1}; {k: v * 10 for k, v in h}["a"]`, "10"},
+        {`{x: x * x for x in 1..3}[3]`, "9"},
+        {`{x: 0 for x in 1..10 if x % 2 == 0}[3] ?? -1`, "-1"},
+        {"[x for x in 1]", "[0]"},
+        {"[x for x in true]", "BOOLEAN is not iterable"},
+        {"[y for x in [1]]", "identifier not found: y"},
+        {"{[x, {}]: 1 for x in [1]}", "unusable as hash key: ARRAY"},
+    }
+
+    for _, test := range tests {
+        evaled := testEval(test.input)
+
+        testExpectedObject(t, test.input, evaled, test.expected)
+    }
+}
+
 func TestHashLiteral(t *testing.T) {
     input := `let two = "two";
     {
@@ -393,21 +1306,21 @@ func TestHashLiteral(t *testing.T) {
         t.Fatalf("Eval didn't return Hash")
     }
 
-    expected := map[object.HashKey]int64 {
-        (&object.String{Value: "one"}).HashKey(): 1,
-        (&object.String{Value: "two"}).HashKey(): 2,
-        (&object.String{Value: "three"}).HashKey(): 3,
-        (&object.Integer{Value: 4}).HashKey(): 4,
-        (&object.Boolean{Value: true}).HashKey(): 5,
-        (&object.Boolean{Value: false}).HashKey(): 6,
+    expected := map[object.Hashable]int64 {
+        &object.String{Value: "one"}: 1,
+        &object.String{Value: "two"}: 2,
+        &object.String{Value: "three"}: 3,
+        &object.Integer{Value: 4}: 4,
+        &object.Boolean{Value: true}: 5,
+        &object.Boolean{Value: false}: 6,
     }
 
-    if len(h.Pairs) != len(expected) {
+    if h.Len() != len(expected) {
         t.Fatalf("Hash has wrong num of pairs")
     }
 
     for expectedKey, expectedVal := range expected {
-        pair, ok := h.Pairs[expectedKey]
+        pair, ok := h.Get(expectedKey)
         if !ok {
             t.Errorf("no pair for given key")
         }
@@ -508,3 +1421,44 @@ func testNullObject(t *testing.T, obj object.Object) bool {
     t.Errorf("obj is not Null object, but got %+v (%T)", obj, obj)
     return false
 }
+
+// 伝播中のエラーで、メッセージがexpectedであることを確かめる
+func testErrorObject(t *testing.T, input string, obj object.Object, expected string) bool {
+    errObj, ok := obj.(*object.Error)
+    if !ok || errObj.IsValue {
+        t.Errorf("%s: no error object returned, got %+v", input, obj)
+        return false
+    }
+    if errObj.Msg != expected {
+        t.Errorf("%s: expected error %q, but got %q", input, expected, errObj.Msg)
+        return false
+    }
+    return true
+}
+
+// expectedの型に応じてobjを確かめる. intとboolはその値、nilはnull、
+// stringは伝播中のエラーであればメッセージと、それ以外であればInspect()の結果と比べる
+func testExpectedObject(t *testing.T, input string, obj object.Object, expected interface{}) bool {
+    switch expected := expected.(type) {
+    case int:
+        return testIntegerObject(t, obj, int64(expected))
+    case int64:
+        return testIntegerObject(t, obj, expected)
+    case bool:
+        return testBooleanObject(t, obj, expected)
+    case string:
+        if errObj, ok := obj.(*object.Error); ok && !errObj.IsValue {
+            return testErrorObject(t, input, obj, expected)
+        }
+        if obj == nil {
+            t.Errorf("%s: expected %s, but got nil", input, expected)
+            return false
+        }
+        if obj.Inspect() != expected {
+            t.Errorf("%s: expected %s, but got %s", input, expected, obj.Inspect())
+            return false
+        }
+        return true
+    }
+    return testNullObject(t, obj)
+}
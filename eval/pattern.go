@@ -0,0 +1,157 @@
+package eval
+
+import (
+    "monkey_interpreter/ast"
+    "monkey_interpreter/object"
+)
+
+type binding struct {
+    name string
+    val object.Object
+}
+
+// patternに従ってvalを分解し、束縛する名前と値をbindingsに追加して返す。
+// 形が合わない場合は何も束縛せずにエラーを返せるよう、環境への反映は呼び出し側で行う
+func destructure(pat ast.Pattern, val object.Object, bindings []binding) ([]binding, *object.Error) {
+    switch pat := pat.(type) {
+    case *ast.Identifier:
+        // `_`は値を捨てるためのワイルドカード
+        if pat.Value == "_" {
+            return bindings, nil
+        }
+        return append(bindings, binding{name: pat.Value, val: val}), nil
+
+    case *ast.ArrayPattern:
+        return destructureArray(pat, val, bindings)
+
+    case *ast.HashPattern:
+        return destructureHash(pat, val, bindings)
+
+    case *ast.LiteralPattern:
+        // リテラルは環境を参照しないので、環境なしで評価できる
+        lit := Eval(pat.Value, nil)
+        if isError(lit) {
+            return nil, lit.(*object.Error)
+        }
+        if !object.Equals(lit, val) {
+            return nil, newErrorKind(valueError, "%s does not match literal pattern %s", val.Inspect(), pat.String())
+        }
+        return bindings, nil
+    }
+
+    return nil, newErrorKind(typeError, "invalid pattern: %s", pat.String())
+}
+
+func destructureArray(pat *ast.ArrayPattern, val object.Object, bindings []binding) ([]binding, *object.Error) {
+    arr, ok := val.(*object.Array)
+    if !ok {
+        return nil, newErrorKind(typeError, "cannot destructure %s with array pattern %s", val.Type(), pat.String())
+    }
+
+    elems := pat.Elems
+    var rest *ast.RestPattern
+    if n := len(elems); n > 0 {
+        if rp, ok := elems[n-1].(*ast.RestPattern); ok {
+            rest = rp
+            elems = elems[:n-1]
+        }
+    }
+
+    if rest == nil && len(arr.Elems) != len(elems) {
+        return nil, newErrorKind(valueError, "array pattern %s expects %d elements, got %d", pat.String(), len(elems), len(arr.Elems))
+    }
+    if rest != nil && len(arr.Elems) < len(elems) {
+        return nil, newErrorKind(valueError, "array pattern %s expects at least %d elements, got %d", pat.String(), len(elems), len(arr.Elems))
+    }
+
+    var err *object.Error
+    for i, elem := range elems {
+        bindings, err = destructure(elem, arr.Elems[i], bindings)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if rest != nil {
+        remain := make([]object.Object, len(arr.Elems) - len(elems))
+        copy(remain, arr.Elems[len(elems):])
+        bindings = append(bindings, binding{name: rest.Name.Value, val: &object.Array{Elems: remain}})
+    }
+
+    return bindings, nil
+}
+
+func destructureHash(pat *ast.HashPattern, val object.Object, bindings []binding) ([]binding, *object.Error) {
+    if err, ok := val.(*object.Error); ok {
+        val = errorHash(err)
+    }
+    h, ok := val.(*object.Hash)
+    if !ok {
+        return nil, newErrorKind(typeError, "cannot destructure %s with hash pattern %s", val.Type(), pat.String())
+    }
+
+    used := object.NewHash()
+    var err *object.Error
+    for _, entry := range pat.Entries {
+        key := &object.String{Value: entry.Key.Value}
+        pair, ok := h.Get(key)
+        if !ok {
+            return nil, newErrorKind(valueError, "hash pattern %s requires key %q", pat.String(), entry.Key.Value)
+        }
+        used.Set(key, TRUE)
+
+        bindings, err = destructure(entry.Value, pair.Value, bindings)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    if pat.Rest != nil {
+        remain := object.NewHash()
+        for _, pair := range h.Pairs() {
+            key := pair.Key.(object.Hashable)
+            if _, ok := used.Get(key); !ok {
+                remain.Set(key, pair.Value)
+            }
+        }
+        bindings = append(bindings, binding{name: pat.Rest.Name.Value, val: remain})
+    }
+
+    return bindings, nil
+}
+
+// armを上から順に試し、patternとguardの両方を満たした最初のarmのbodyを評価する.
+// patternで束縛した名前はarmごとに新しい環境に置く
+func evalMatchExpression(me *ast.MatchExpression, env *object.Env) object.Object {
+    subject := Eval(me.Subject, env)
+    if isError(subject) {
+        return subject
+    }
+
+    for _, arm := range me.Arms {
+        // 形が合わないことによるエラーは、単に次のarmを試す合図として扱う
+        bindings, err := destructure(arm.Pattern, subject, nil)
+        if err != nil {
+            continue
+        }
+
+        armEnv := object.NewEnclosedEnv(env)
+        for _, b := range bindings {
+            armEnv.Set(b.name, b.val)
+        }
+
+        if arm.Guard != nil {
+            guard := Eval(arm.Guard, armEnv)
+            if isError(guard) {
+                return guard
+            }
+            if !isTruthly(guard) {
+                continue
+            }
+        }
+
+        return Eval(arm.Body, armEnv)
+    }
+
+    return newErrorKind(matchError, "no match arm for value %s", subject.Inspect())
+}
@@ -7,16 +7,24 @@ type Lexer struct {
     position int // 現在読む位置
     readPosition int // 次に読む位置
     ch byte // 現在検査中の文字
+    line int // chの行番号
+    column int // chの列番号
 }
 
 func New(input string) *Lexer {
-    l := &Lexer{input: input}
+    l := &Lexer{input: input, line: 1}
     l.readChar()
     return l
 }
 
 // Lexer構造体のメソッド, *がついているので参照渡しで、メソッドに渡される
 func (l *Lexer) readChar() {
+    if l.ch == '\n' {
+        l.line++
+        l.column = 0
+    }
+    l.column++
+
     if l.readPosition >= len(l.input) {
         l.ch = 0
     } else {
@@ -31,23 +39,51 @@ func newToken(tokentype token.TokenType, ch byte) token.Token {
 }
 
 func (l *Lexer) NextToken() token.Token {
-    var tok token.Token
-
     for isSpace(l.ch) {
         l.readChar()
     }
 
+    line, column := l.line, l.column
+    tok := l.readToken()
+    tok.Line = line
+    tok.Column = column
+
+    return tok
+}
+
+func (l *Lexer) readToken() token.Token {
+    var tok token.Token
+
     switch l.ch {
     case '=':
         if l.readPeep() == '=' {
             l.readChar()
             tok.Type = token.EQ
             tok.Literal = "=="
+        } else if l.readPeep() == '>' {
+            l.readChar()
+            tok.Type = token.ARROW
+            tok.Literal = "=>"
         } else {
             tok = newToken(token.ASSGIN, l.ch)
         }
     case ';':
         tok = newToken(token.SEMICOLON, l.ch)
+    case '.':
+        if l.readPeep() == '.' {
+            l.readChar()
+            if l.readPeep() == '.' {
+                l.readChar()
+                tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+            } else if l.readPeep() == '<' {
+                l.readChar()
+                tok = token.Token{Type: token.RANGE_EXCL, Literal: "..<"}
+            } else {
+                tok = token.Token{Type: token.RANGE, Literal: ".."}
+            }
+        } else {
+            tok = newToken(token.ILLGAL, l.ch)
+        }
     case ':':
         tok = newToken(token.COLON, l.ch)
     case '(':
@@ -57,17 +93,36 @@ func (l *Lexer) NextToken() token.Token {
     case ',':
         tok = newToken(token.COMMA, l.ch)
     case '+':
-        tok = newToken(token.PLUS, l.ch)
+        tok = l.readOperator(token.PLUS, token.PLUS_ASSIGN)
     case '-':
-        tok = newToken(token.MINUS, l.ch)
+        tok = l.readOperator(token.MINUS, token.MINUS_ASSIGN)
     case '*':
-        tok = newToken(token.MUL, l.ch)
+        tok = l.readOperator(token.MUL, token.MUL_ASSIGN)
     case '/':
-        tok = newToken(token.DIV, l.ch)
+        if l.readPeep() == '/' {
+            l.readChar()
+            tok = l.readOperator(token.FLOORDIV, token.FLOORDIV_ASSIGN)
+        } else {
+            tok = l.readOperator(token.DIV, token.DIV_ASSIGN)
+        }
+    case '%':
+        tok = l.readOperator(token.MOD, token.MOD_ASSIGN)
     case '<':
-        tok = newToken(token.LT, l.ch)
+        if l.readPeep() == '=' {
+            l.readChar()
+            tok.Type = token.LE
+            tok.Literal = "<="
+        } else {
+            tok = newToken(token.LT, l.ch)
+        }
     case '>':
-        tok = newToken(token.GT, l.ch)
+        if l.readPeep() == '=' {
+            l.readChar()
+            tok.Type = token.GE
+            tok.Literal = ">="
+        } else {
+            tok = newToken(token.GT, l.ch)
+        }
     case '!':
         if l.readPeep() == '=' {
             l.readChar()
@@ -76,6 +131,38 @@ func (l *Lexer) NextToken() token.Token {
         } else {
             tok = newToken(token.BANG, l.ch)
         }
+    case '?':
+        if l.readPeep() == '?' {
+            l.readChar()
+            tok.Type = token.NULLISH
+            tok.Literal = "??"
+        } else if l.readPeep() == '.' {
+            l.readChar()
+            tok.Type = token.OPTIONAL_CHAIN
+            tok.Literal = "?."
+        } else {
+            tok = newToken(token.QUESTION, l.ch)
+        }
+    case '&':
+        if l.readPeep() == '&' {
+            l.readChar()
+            tok.Type = token.AND
+            tok.Literal = "&&"
+        } else {
+            tok = newToken(token.ILLGAL, l.ch)
+        }
+    case '|':
+        if l.readPeep() == '|' {
+            l.readChar()
+            tok.Type = token.OR
+            tok.Literal = "||"
+        } else if l.readPeep() == '>' {
+            l.readChar()
+            tok.Type = token.PIPE
+            tok.Literal = "|>"
+        } else {
+            tok = newToken(token.ILLGAL, l.ch)
+        }
     case '{':
         tok = newToken(token.LBRACE, l.ch)
     case '}':
@@ -109,6 +196,16 @@ func (l *Lexer) NextToken() token.Token {
     return tok
 }
 
+// 次の文字が`=`であれば複合代入演算子(`+=`など)として読む。
+// 演算子のTokenTypeはそのままリテラルとして使える
+func (l *Lexer) readOperator(op, assignOp token.TokenType) token.Token {
+    if l.readPeep() == '=' {
+        l.readChar()
+        return token.Token{Type: assignOp, Literal: string(assignOp)}
+    }
+    return token.Token{Type: op, Literal: string(op)}
+}
+
 func (l *Lexer) readIdentifier() string {
     var start int = l.position
     var i int = start
@@ -121,9 +218,19 @@ func (l *Lexer) readIdentifier() string {
     return ident
 }
 
+// 10進数に加え、0x(16進), 0o(8進), 0b(2進)の接頭辞と`_`による桁区切りを読む。
+// 桁の妥当性はここでは検査せず、parserで診断する
 func (l *Lexer) readNum() string {
     var start int = l.position
-    for isDigit(l.ch) {
+    if l.ch == '0' && isBasePrefix(l.readPeep()) {
+        l.readChar()
+        l.readChar()
+        for isLetter(l.ch) || isDigit(l.ch) {
+            l.readChar()
+        }
+        return l.input[start: l.position]
+    }
+    for isDigit(l.ch) || l.ch == '_' {
         l.readChar()
     }
     return l.input[start: l.position]
@@ -161,3 +268,11 @@ func isSpace(c byte) bool {
 func isDigit(c byte) bool {
     return '0' <= c && c <= '9'
 }
+
+func isBasePrefix(c byte) bool {
+    switch c {
+    case 'x', 'X', 'o', 'O', 'b', 'B':
+        return true
+    }
+    return false
+}
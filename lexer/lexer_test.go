@@ -29,6 +29,17 @@ func TestNextToken(t *testing.T) {
     "rom bom"
     [1, 2];
     {"foo": "bar"}
+    7 % 2 // 3;
+    a && b || c;
+    1 <= 2 >= 3;
+    while for in break continue const
+    [a, ...b]
+    += -= *= /= //= %=
+    match => ==
+    try catch finally throw
+    a ? b : c ?? d?.[e]
+    x |> f || g
+    1..10 0..<n
     `
     tests := []struct {
         expectedType token.TokenType
@@ -120,6 +131,71 @@ func TestNextToken(t *testing.T) {
         {token.COLON, ":"},
         {token.STRING, "bar"},
         {token.RBRACE, "}"},
+        {token.INT, "7"},
+        {token.MOD, "%"},
+        {token.INT, "2"},
+        {token.FLOORDIV, "//"},
+        {token.INT, "3"},
+        {token.SEMICOLON, ";"},
+        {token.IDENT, "a"},
+        {token.AND, "&&"},
+        {token.IDENT, "b"},
+        {token.OR, "||"},
+        {token.IDENT, "c"},
+        {token.SEMICOLON, ";"},
+        {token.INT, "1"},
+        {token.LE, "<="},
+        {token.INT, "2"},
+        {token.GE, ">="},
+        {token.INT, "3"},
+        {token.SEMICOLON, ";"},
+        {token.WHILE, "while"},
+        {token.FOR, "for"},
+        {token.IN, "in"},
+        {token.BREAK, "break"},
+        {token.CONTINUE, "continue"},
+        {token.CONST, "const"},
+        {token.LBRACKET, "["},
+        {token.IDENT, "a"},
+        {token.COMMA, ","},
+        {token.ELLIPSIS, "..."},
+        {token.IDENT, "b"},
+        {token.RBRACKET, "]"},
+        {token.PLUS_ASSIGN, "+="},
+        {token.MINUS_ASSIGN, "-="},
+        {token.MUL_ASSIGN, "*="},
+        {token.DIV_ASSIGN, "/="},
+        {token.FLOORDIV_ASSIGN, "//="},
+        {token.MOD_ASSIGN, "%="},
+        {token.MATCH, "match"},
+        {token.ARROW, "=>"},
+        {token.EQ, "=="},
+        {token.TRY, "try"},
+        {token.CATCH, "catch"},
+        {token.FINALLY, "finally"},
+        {token.THROW, "throw"},
+        {token.IDENT, "a"},
+        {token.QUESTION, "?"},
+        {token.IDENT, "b"},
+        {token.COLON, ":"},
+        {token.IDENT, "c"},
+        {token.NULLISH, "??"},
+        {token.IDENT, "d"},
+        {token.OPTIONAL_CHAIN, "?."},
+        {token.LBRACKET, "["},
+        {token.IDENT, "e"},
+        {token.RBRACKET, "]"},
+        {token.IDENT, "x"},
+        {token.PIPE, "|>"},
+        {token.IDENT, "f"},
+        {token.OR, "||"},
+        {token.IDENT, "g"},
+        {token.INT, "1"},
+        {token.RANGE, ".."},
+        {token.INT, "10"},
+        {token.INT, "0"},
+        {token.RANGE_EXCL, "..<"},
+        {token.IDENT, "n"},
         {token.EOF, ""},
     }
 
@@ -140,3 +216,76 @@ func TestNextToken(t *testing.T) {
         }
     }
 }
+
+func TestNumberLiterals(t *testing.T) {
+    input := `0x1F 0o17 0b1010 1_000_000 0xZZ 42;`
+
+    tests := []struct {
+        expectedType token.TokenType
+        expectedLiteral string
+    }{
+        {token.INT, "0x1F"},
+        {token.INT, "0o17"},
+        {token.INT, "0b1010"},
+        {token.INT, "1_000_000"},
+        {token.INT, "0xZZ"},
+        {token.INT, "42"},
+        {token.SEMICOLON, ";"},
+        {token.EOF, ""},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Type != tt.expectedType {
+            t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+                i, tt.expectedType, tok.Type)
+        }
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, tt.expectedLiteral, tok.Literal)
+        }
+    }
+}
+
+func TestTokenPositions(t *testing.T) {
+    input := `let x = 10;
+  x >= 2
+"str" fn`
+
+    tests := []struct {
+        expectedLiteral string
+        expectedLine int
+        expectedColumn int
+    }{
+        {"let", 1, 1},
+        {"x", 1, 5},
+        {"=", 1, 7},
+        {"10", 1, 9},
+        {";", 1, 11},
+        {"x", 2, 3},
+        {">=", 2, 5},
+        {"2", 2, 8},
+        {"str", 3, 1},
+        {"fn", 3, 7},
+    }
+
+    l := New(input)
+
+    for i, tt := range tests {
+        tok := l.NextToken()
+
+        if tok.Literal != tt.expectedLiteral {
+            t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+                i, tt.expectedLiteral, tok.Literal)
+        }
+
+        if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+            t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+                i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+        }
+    }
+}
@@ -39,9 +39,25 @@ func exec(filename string) {
 
     l := lexer.New(input)
     p := parser.New(l)
+    p.SetBuiltins(eval.BuiltinNames())
 
     program := p.ParseProgram()
+    for _, msg := range p.Warnings() {
+        fmt.Fprintf(os.Stderr, "%s: warning: %s\n", filename, msg)
+    }
+    if len(p.Errors()) != 0 {
+        for _, msg := range p.Errors() {
+            fmt.Fprintf(os.Stderr, "%s: %s\n", filename, msg)
+        }
+        os.Exit(1)
+    }
 
     env := object.NewEnv()
-    eval.Eval(program, env)
+    evaled := eval.Eval(program, env)
+
+    // catchされなかったエラーは発生位置とともに報告する
+    if err, ok := evaled.(*object.Error); ok && !err.IsValue {
+        fmt.Fprintf(os.Stderr, "%s:%d:%d: %s\n", filename, err.Line, err.Column, err.Inspect())
+        os.Exit(1)
+    }
 }
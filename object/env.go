@@ -2,12 +2,14 @@ package object
 
 type Env struct {
     store map[string]Object
+    consts map[string]bool // constで束縛された名前
     outer *Env
 }
 
 func NewEnv() *Env {
     s := make(map[string]Object)
-    return &Env{store: s}
+    c := make(map[string]bool)
+    return &Env{store: s, consts: c}
 }
 
 func NewEnclosedEnv(outer *Env) *Env {
@@ -24,7 +26,29 @@ func (e *Env) Get(name string) (Object, bool) {
     return obj, ok
 }
 
+// nameが束縛されている最も内側の環境を返す。どこにも無ければnil
+func (e *Env) Resolve(name string) *Env {
+    if _, ok := e.store[name]; ok {
+        return e
+    }
+    if e.outer != nil {
+        return e.outer.Resolve(name)
+    }
+    return nil
+}
+
 func (e *Env) Set(name string, obj Object) Object {
     e.store[name] = obj
     return obj
 }
+
+// 再代入, 再宣言できない束縛を作る
+func (e *Env) SetConst(name string, obj Object) Object {
+    e.consts[name] = true
+    return e.Set(name, obj)
+}
+
+// nameがこの環境でconstとして束縛されているか. 外側の環境は調べない
+func (e *Env) IsConst(name string) bool {
+    return e.consts[name]
+}
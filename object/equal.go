@@ -0,0 +1,79 @@
+package object
+
+// ==, != の意味を全てのオブジェクトで揃えるための比較。
+// 配列とハッシュは要素を再帰的に比較し、関数などそれ以外は同一のオブジェクトの場合のみ等しい
+func Equals(a, b Object) bool {
+    // 同じものであれば中身を比べるまでもない
+    if a == b {
+        return true
+    }
+
+    switch a := a.(type) {
+    case *Integer:
+        b, ok := b.(*Integer)
+        return ok && a.Value == b.Value
+    case *BigInteger:
+        b, ok := b.(*BigInteger)
+        return ok && a.Value.Cmp(b.Value) == 0
+    case *String:
+        b, ok := b.(*String)
+        return ok && a.Value == b.Value
+    case *Boolean:
+        b, ok := b.(*Boolean)
+        return ok && a.Value == b.Value
+    case *Null:
+        _, ok := b.(*Null)
+        return ok
+    case *Range:
+        b, ok := b.(*Range)
+        if !ok {
+            return false
+        }
+        // 同じ整数の列を表していれば、作り方によらず等しい
+        if a.Len() != b.Len() {
+            return false
+        }
+        if a.Len() == 0 {
+            return true
+        }
+        return a.Start == b.Start && (a.Len() == 1 || a.Step == b.Step)
+    case *Array:
+        b, ok := b.(*Array)
+        if !ok || len(a.Elems) != len(b.Elems) {
+            return false
+        }
+        for i := range a.Elems {
+            if !Equals(a.Elems[i], b.Elems[i]) {
+                return false
+            }
+        }
+        return true
+    case *Hash:
+        b, ok := b.(*Hash)
+        if !ok || a.Len() != b.Len() {
+            return false
+        }
+        // キーの順序は比較しない
+        for e := a.head; e != nil; e = e.next {
+            pb, ok := b.Get(e.pair.Key.(Hashable))
+            if !ok || !Equals(e.pair.Value, pb.Value) {
+                return false
+            }
+        }
+        return true
+    case *Set:
+        b, ok := b.(*Set)
+        if !ok || a.Len() != b.Len() {
+            return false
+        }
+        // 要素の順序は比較しない
+        for _, elem := range a.Elems() {
+            if !b.Has(elem.(Hashable)) {
+                return false
+            }
+        }
+        return true
+    default:
+        return a == b
+    }
+}
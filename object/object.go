@@ -4,7 +4,10 @@ import (
     "fmt"
     "bytes"
     "strings"
+    "hash"
     "hash/fnv"
+    "encoding/binary"
+    "math/big"
     "monkey_interpreter/ast"
 )
 
@@ -12,14 +15,19 @@ type ObjectType string
 
 const (
     INTEGER_OBJ = "INTEGER"
+    BIGINT_OBJ = "BIGINT"
     STRING_OBJ = "STRING"
     BOOLEAN_OBJ = "BOOLEAN"
     NULL_OBJ = "NULL"
     RETURN_VALUE_OBJ = "RETURN_VALUE"
+    BREAK_OBJ = "BREAK"
+    CONTINUE_OBJ = "CONTINUE"
     FUNCTION_OBJ = "FUNCTION"
     BUILTIN_OBJ = "BUILTIN"
     ARRAY_OBJ = "ARRAY"
     HASH_OBJ = "HASH"
+    RANGE_OBJ = "RANGE"
+    SET_OBJ = "SET"
     ERROR_OBJ = "ERROR"
 )
 
@@ -39,6 +47,19 @@ func (i *Integer) Type() ObjectType {
     return INTEGER_OBJ
 }
 
+// int64に収まらない整数。算術演算がオーバーフローした時に自動で昇格される。
+// int64に収まる値は常にIntegerで表すため、両者の値が重なることはない
+type BigInteger struct {
+    Value *big.Int
+}
+
+func (bi *BigInteger) Inspect() string {
+    return bi.Value.String()
+}
+func (bi *BigInteger) Type() ObjectType {
+    return BIGINT_OBJ
+}
+
 type String struct {
     Value string
 }
@@ -51,7 +72,7 @@ func (s *String) Type() ObjectType {
 }
 
 type Function struct {
-    Params []*ast.Identifier
+    Params []ast.Pattern
     Body *ast.BlockStatement
     Env *Env
 }
@@ -99,6 +120,25 @@ func (rc *ReturnValue) Inspect() string {
     return rc.Value.Inspect()
 }
 
+// break, continueが評価されたことを、ReturnValueと同様に外側のループまで伝える
+type Break struct {}
+
+func (b *Break) Type() ObjectType {
+    return BREAK_OBJ
+}
+func (b *Break) Inspect() string {
+    return "break"
+}
+
+type Continue struct {}
+
+func (c *Continue) Type() ObjectType {
+    return CONTINUE_OBJ
+}
+func (c *Continue) Inspect() string {
+    return "continue"
+}
+
 type Null struct {}
 
 func (n *Null) Type() ObjectType {
@@ -110,13 +150,23 @@ func (n *Null) Inspect() string {
 
 type Error struct {
     Msg string
+    // TypeError, NameErrorなどのエラーの種類
+    Kind string
+    // エラーが発生したソース上の位置. 不明な場合は0
+    Line int
+    Column int
+    // throw文で投げられた値. 処理系が生成したエラーではnil
+    Value Object
+    // trueの場合は普通の値として扱い、評価を中断させずに変数に入れたり関数から返したりできる.
+    // error()で作ったエラーとcatchで受け取ったエラーがこれにあたる
+    IsValue bool
 }
 
 func (e *Error) Type() ObjectType {
     return ERROR_OBJ
 }
 func (e *Error) Inspect() string {
-    return "ERROR: " + e.Msg
+    return e.Kind + ": " + e.Msg
 }
 
 // 引数に0個以上のObject型をとり、返り値にObject型を返す関数
@@ -133,8 +183,52 @@ func (b *Builtin) Inspect() string {
     return "builtin function"
 }
 
+// StartからStepずつ進み、Stopの手前で止まる整数の列. 要素を持たず、必要な時に計算する.
+// Stepは0以外で、負の場合は減っていく. Inclusiveは`..`で作られたかどうかで、Inspectの表示にのみ用いる
+type Range struct {
+    Start int64
+    Stop int64
+    Step int64
+    Inclusive bool
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+    switch {
+    case r.Step != 1:
+        return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+    case r.Inclusive:
+        return fmt.Sprintf("%d..%d", r.Start, r.Stop - 1)
+    default:
+        return fmt.Sprintf("%d..<%d", r.Start, r.Stop)
+    }
+}
+
+// 要素数. Stopに向かって進めない場合は空.
+// 両端の差はint64に収まらないことがあるので、uint64で計算する
+func (r *Range) Len() uint64 {
+    if r.Step > 0 && r.Start < r.Stop {
+        return (uint64(r.Stop) - uint64(r.Start) - 1) / uint64(r.Step) + 1
+    }
+    if r.Step < 0 && r.Start > r.Stop {
+        return (uint64(r.Start) - uint64(r.Stop) - 1) / -uint64(r.Step) + 1
+    }
+    return 0
+}
+
+// i番目(0始まり)の要素. 範囲外の場合はfalseを返す
+func (r *Range) At(i uint64) (int64, bool) {
+    if r.Len() <= i {
+        return 0, false
+    }
+    // 途中の積があふれても、結果はStartとStopの間に収まるので正しい値になる
+    return r.Start + int64(i) * r.Step, true
+}
+
 type Array struct {
     Elems []Object
+    // freezeで作られた配列は変更できない
+    Frozen bool
 }
 
 func (a *Array) Type() ObjectType {
@@ -161,6 +255,7 @@ type HashKey struct {
 }
 
 type Hashable interface {
+    Object
     HashKey() HashKey
 }
 
@@ -177,10 +272,103 @@ func (b *Boolean) HashKey() HashKey {
 func (i *Integer) HashKey() HashKey {
     return HashKey{Type: INTEGER_OBJ, Value: uint64(i.Value)}
 }
+func (bi *BigInteger) HashKey() HashKey {
+    h := fnv.New64a()
+    if bi.Value.Sign() < 0 {
+        h.Write([]byte{'-'})
+    }
+    h.Write(bi.Value.Bytes())
+    return HashKey{Type: BIGINT_OBJ, Value: h.Sum64()}
+}
+// 文字列のハッシュ値. テストでキーの衝突を起こせるよう変数にしてある
+var hashString = func(s string) uint64 {
+    h := fnv.New64a()
+    h.Write([]byte(s))
+    return h.Sum64()
+}
+
 func (s *String) HashKey() HashKey {
+    return HashKey{Type: STRING_OBJ, Value: hashString(s.Value)}
+}
+
+func writeHashKey(h hash.Hash64, hk HashKey) {
+    var buf [8]byte
+    h.Write([]byte(hk.Type))
+    binary.LittleEndian.PutUint64(buf[:], hk.Value)
+    h.Write(buf[:])
+}
+
+// 要素のHashKeyを順に混ぜる. 要素が全てHashableであることはIsHashableで確かめておく
+func (a *Array) HashKey() HashKey {
     h := fnv.New64a()
-    h.Write([]byte(s.Value))
-    return HashKey{Type: STRING_OBJ, Value: h.Sum64()}
+    for _, elem := range a.Elems {
+        writeHashKey(h, elem.(Hashable).HashKey())
+    }
+    return HashKey{Type: ARRAY_OBJ, Value: h.Sum64()}
+}
+
+// Equalsと同じくキーの順序に依存しないよう、ペアごとの値を足し合わせる
+func (h *Hash) HashKey() HashKey {
+    var sum uint64
+    for e := h.head; e != nil; e = e.next {
+        ph := fnv.New64a()
+        writeHashKey(ph, e.pair.Key.(Hashable).HashKey())
+        writeHashKey(ph, e.pair.Value.(Hashable).HashKey())
+        sum += ph.Sum64()
+    }
+    return HashKey{Type: HASH_OBJ, Value: sum}
+}
+
+// objがハッシュのキーに使えるかどうか. 配列は全ての要素が、ハッシュは凍結済みで全ての値が使える必要がある
+func IsHashable(obj Object) bool {
+    switch obj := obj.(type) {
+    case *Integer, *BigInteger, *Boolean, *String:
+        return true
+    case *Array:
+        for _, elem := range obj.Elems {
+            if !IsHashable(elem) {
+                return false
+            }
+        }
+        return true
+    case *Hash:
+        if !obj.Frozen {
+            return false
+        }
+        for e := obj.head; e != nil; e = e.next {
+            if !IsHashable(e.pair.Value) {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// objの凍結したコピーを中身まで再帰的に作る. 既に凍結済みのものや配列・ハッシュ以外はそのまま返す
+func Freeze(obj Object) Object {
+    switch obj := obj.(type) {
+    case *Array:
+        if obj.Frozen {
+            return obj
+        }
+        elems := make([]Object, len(obj.Elems))
+        for i, elem := range obj.Elems {
+            elems[i] = Freeze(elem)
+        }
+        return &Array{Elems: elems, Frozen: true}
+    case *Hash:
+        if obj.Frozen {
+            return obj
+        }
+        res := NewHash()
+        for e := obj.head; e != nil; e = e.next {
+            res.Set(Freeze(e.pair.Key).(Hashable), Freeze(e.pair.Value))
+        }
+        res.Frozen = true
+        return res
+    }
+    return obj
 }
 
 type HashPair struct {
@@ -188,8 +376,111 @@ type HashPair struct {
     Value Object
 }
 
+// 挿入順を保つハッシュ. キーからの検索はmapで、順序は双方向リストで管理する.
+// ゼロ値は空のハッシュとして使える
 type Hash struct {
-    Pairs map[HashKey]HashPair
+    // HashKeyが衝突したキーは同じバケットに入り、キー自体の比較で区別する
+    index map[HashKey][]*hashEntry
+    head *hashEntry
+    tail *hashEntry
+    count int
+    // freezeで作られたハッシュは変更できず、ハッシュのキーにも使える
+    Frozen bool
+}
+
+type hashEntry struct {
+    pair HashPair
+    prev *hashEntry
+    next *hashEntry
+}
+
+func NewHash() *Hash {
+    return &Hash{index: map[HashKey][]*hashEntry{}}
+}
+
+// keyと等しいキーを持つエントリをバケットから探す
+func (h *Hash) lookup(key Hashable) (HashKey, int) {
+    hk := key.HashKey()
+    for i, e := range h.index[hk] {
+        if Equals(e.pair.Key, key) {
+            return hk, i
+        }
+    }
+    return hk, -1
+}
+
+func (h *Hash) Get(key Hashable) (HashPair, bool) {
+    hk, i := h.lookup(key)
+    if i < 0 {
+        return HashPair{}, false
+    }
+    return h.index[hk][i].pair, true
+}
+
+// 既にあるキーは順序を変えずに値だけを置き換え、新しいキーは末尾に追加する
+func (h *Hash) Set(key Hashable, val Object) {
+    hk, i := h.lookup(key)
+    if i >= 0 {
+        h.index[hk][i].pair.Value = val
+        return
+    }
+
+    if h.index == nil {
+        h.index = map[HashKey][]*hashEntry{}
+    }
+    // 後からキーを書き換えられて表が壊れないよう、凍結したコピーを保持する
+    key = Freeze(key).(Hashable)
+    e := &hashEntry{pair: HashPair{Key: key, Value: val}, prev: h.tail}
+    if h.tail != nil {
+        h.tail.next = e
+    } else {
+        h.head = e
+    }
+    h.tail = e
+    h.index[hk] = append(h.index[hk], e)
+    h.count++
+}
+
+// keyを取り除く. keyがなければfalseを返す
+func (h *Hash) Delete(key Hashable) bool {
+    hk, i := h.lookup(key)
+    if i < 0 {
+        return false
+    }
+    bucket := h.index[hk]
+    e := bucket[i]
+
+    if e.prev != nil {
+        e.prev.next = e.next
+    } else {
+        h.head = e.next
+    }
+    if e.next != nil {
+        e.next.prev = e.prev
+    } else {
+        h.tail = e.prev
+    }
+
+    if len(bucket) == 1 {
+        delete(h.index, hk)
+    } else {
+        h.index[hk] = append(bucket[:i:i], bucket[i+1:]...)
+    }
+    h.count--
+    return true
+}
+
+func (h *Hash) Len() int {
+    return h.count
+}
+
+// 全てのペアを挿入順に返す
+func (h *Hash) Pairs() []HashPair {
+    pairs := make([]HashPair, 0, h.count)
+    for e := h.head; e != nil; e = e.next {
+        pairs = append(pairs, e.pair)
+    }
+    return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -198,7 +489,7 @@ func (h *Hash) Inspect() string {
     out.WriteString("{")
 
     pairs := []string{}
-    for _, pair := range h.Pairs {
+    for _, pair := range h.Pairs() {
         pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
     }
     out.WriteString(strings.Join(pairs, ", "))
@@ -207,3 +498,64 @@ func (h *Hash) Inspect() string {
 
     return out.String()
 }
+
+// 要素をキー、値を持たないハッシュとして、挿入順を保つ集合.
+// ゼロ値は空の集合として使える
+type Set struct {
+    elems Hash
+}
+
+func NewSet() *Set {
+    return &Set{}
+}
+
+func (s *Set) Has(elem Hashable) bool {
+    _, ok := s.elems.Get(elem)
+    return ok
+}
+
+// 既にある要素を追加しても順序は変わらない
+func (s *Set) Add(elem Hashable) {
+    if !s.Has(elem) {
+        s.elems.Set(elem, nil)
+    }
+}
+
+// elemを取り除く. elemがなければfalseを返す
+func (s *Set) Remove(elem Hashable) bool {
+    return s.elems.Delete(elem)
+}
+
+func (s *Set) Len() int {
+    return s.elems.Len()
+}
+
+// 全ての要素を挿入順に返す
+func (s *Set) Elems() []Object {
+    elems := make([]Object, 0, s.elems.Len())
+    for e := s.elems.head; e != nil; e = e.next {
+        elems = append(elems, e.pair.Key)
+    }
+    return elems
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+    // `{}`は空のハッシュを表すので、空の集合は作り方と同じ形で表示する
+    if s.Len() == 0 {
+        return "set()"
+    }
+
+    var out bytes.Buffer
+    out.WriteString("{")
+
+    elems := []string{}
+    for _, elem := range s.Elems() {
+        elems = append(elems, elem.Inspect())
+    }
+    out.WriteString(strings.Join(elems, ", "))
+
+    out.WriteString("}")
+
+    return out.String()
+}
@@ -1,6 +1,9 @@
 package object
 
-import "testing"
+import (
+    "math/big"
+    "testing"
+)
 
 func TestStringHashKey(t *testing.T) {
     hello1 := &String{Value: "Hello World"}
@@ -20,3 +23,223 @@ func TestStringHashKey(t *testing.T) {
         t.Errorf("strings with different content have same hash keys")
     }
 }
+
+func TestBigIntegerHashKey(t *testing.T) {
+    a, _ := new(big.Int).SetString("18446744073709551616", 10)
+    b, _ := new(big.Int).SetString("18446744073709551616", 10)
+    c, _ := new(big.Int).SetString("-18446744073709551616", 10)
+
+    big1 := &BigInteger{Value: a}
+    big2 := &BigInteger{Value: b}
+    neg := &BigInteger{Value: c}
+
+    if big1.HashKey() != big2.HashKey() {
+        t.Errorf("big integers with same value have different hash keys")
+    }
+
+    if big1.HashKey() == neg.HashKey() {
+        t.Errorf("big integers with different sign have same hash keys")
+    }
+}
+
+func TestCompositeHashKey(t *testing.T) {
+    arr := func(elems ...Object) *Array { return &Array{Elems: elems} }
+    one := &Integer{Value: 1}
+    two := &Integer{Value: 2}
+
+    if arr(one, two).HashKey() != arr(one, two).HashKey() {
+        t.Errorf("arrays with same elements have different hash keys")
+    }
+    if arr(one, two).HashKey() == arr(two, one).HashKey() {
+        t.Errorf("arrays with different order have same hash keys")
+    }
+    if arr(arr(one), two).HashKey() == arr(one, arr(two)).HashKey() {
+        t.Errorf("arrays with different nesting have same hash keys")
+    }
+
+    h1 := NewHash()
+    h1.Set(&String{Value: "x"}, one)
+    h1.Set(&String{Value: "y"}, two)
+    h2 := NewHash()
+    h2.Set(&String{Value: "y"}, two)
+    h2.Set(&String{Value: "x"}, one)
+    if h1.HashKey() != h2.HashKey() {
+        t.Errorf("hashes with same pairs have different hash keys")
+    }
+
+    if IsHashable(h1) {
+        t.Errorf("unfrozen hash must not be hashable")
+    }
+    if !IsHashable(Freeze(h1)) || !IsHashable(arr(one, Freeze(h2))) {
+        t.Errorf("frozen hash must be hashable")
+    }
+    if IsHashable(arr(one, h1)) {
+        t.Errorf("array containing unfrozen hash must not be hashable")
+    }
+
+    // キーとして入れた配列を後から書き換えても表は壊れない
+    key := arr(one, two)
+    h := NewHash()
+    h.Set(key, one)
+    key.Elems[0] = two
+    if _, ok := h.Get(arr(one, two)); !ok {
+        t.Errorf("stored key must be a snapshot")
+    }
+}
+
+func TestEquals(t *testing.T) {
+    one := &Integer{Value: 1}
+    fn := &Function{}
+
+    tests := []struct {
+        a, b Object
+        expected bool
+    }{
+        {&Integer{Value: 1}, &Integer{Value: 1}, true},
+        {&Integer{Value: 1}, &String{Value: "1"}, false},
+        {&Boolean{Value: true}, &Boolean{Value: true}, true},
+        {&Null{}, &Null{}, true},
+        {&Array{Elems: []Object{one, &String{Value: "a"}}}, &Array{Elems: []Object{&Integer{Value: 1}, &String{Value: "a"}}}, true},
+        {&Array{Elems: []Object{one}}, &Array{Elems: []Object{}}, false},
+        {fn, fn, true},
+        {fn, &Function{}, false},
+        {newSet(one, &String{Value: "a"}), newSet(&String{Value: "a"}, &Integer{Value: 1}), true},
+        {newSet(one), newSet(one, &String{Value: "a"}), false},
+        {newSet(one), &Array{Elems: []Object{one}}, false},
+    }
+
+    for i, test := range tests {
+        if Equals(test.a, test.b) != test.expected {
+            t.Errorf("tests[%d] - Equals(%s, %s) expected %t", i, test.a.Inspect(), test.b.Inspect(), test.expected)
+        }
+    }
+}
+
+func newSet(elems ...Hashable) *Set {
+    s := NewSet()
+    for _, elem := range elems {
+        s.Add(elem)
+    }
+    return s
+}
+
+func TestSet(t *testing.T) {
+    a := &String{Value: "a"}
+    s := newSet(&Integer{Value: 2}, a, &Integer{Value: 1}, &String{Value: "a"})
+    if s.Len() != 3 {
+        t.Fatalf("expected 3 elems, but got %d", s.Len())
+    }
+    if s.Inspect() != "{2, a, 1}" {
+        t.Errorf("expected insertion order, but got %s", s.Inspect())
+    }
+    if !s.Has(&Integer{Value: 1}) || s.Has(&Integer{Value: 3}) {
+        t.Errorf("Has returned wrong result")
+    }
+    if !s.Remove(a) || s.Remove(a) {
+        t.Errorf("Remove must return true only once")
+    }
+
+    var zero Set
+    if zero.Inspect() != "set()" {
+        t.Errorf("empty set must be shown as set(), got %s", zero.Inspect())
+    }
+}
+
+func TestHashOrder(t *testing.T) {
+    h := NewHash()
+    for _, name := range []string{"c", "a", "b", "d"} {
+        key := &String{Value: name}
+        h.Set(key, key)
+    }
+
+    a := &String{Value: "a"}
+    h.Set(a, &Integer{Value: 1})
+    h.Delete(&String{Value: "c"})
+    h.Delete(&String{Value: "d"})
+    if h.Delete(&String{Value: "x"}) {
+        t.Errorf("deleting a missing key must return false")
+    }
+    e := &String{Value: "e"}
+    h.Set(e, e)
+
+    if h.Len() != 3 {
+        t.Fatalf("expected 3 pairs, but got %d", h.Len())
+    }
+    if h.Inspect() != "{a: 1, b: b, e: e}" {
+        t.Errorf("expected insertion order, but got %s", h.Inspect())
+    }
+    if pair, ok := h.Get(a); !ok || pair.Value.Inspect() != "1" {
+        t.Errorf("Get returned %v, %t", pair, ok)
+    }
+
+    var zero Hash
+    zero.Set(a, a)
+    if zero.Inspect() != "{a: a}" {
+        t.Errorf("zero value Hash must be usable, got %s", zero.Inspect())
+    }
+}
+
+func TestHashCollision(t *testing.T) {
+    // 全ての文字列が同じHashKeyを持つようにする
+    orig := hashString
+    hashString = func(string) uint64 { return 42 }
+    defer func() { hashString = orig }()
+
+    str := func(s string) *String { return &String{Value: s} }
+    if str("a").HashKey() != str("b").HashKey() {
+        t.Fatalf("keys must collide")
+    }
+
+    // 検索や上書きには毎回新しく作ったキーを使い、同一性ではなく等価性で見つかることを確かめる
+    h := NewHash()
+    h.Set(str("a"), &Integer{Value: 1})
+    h.Set(str("b"), &Integer{Value: 2})
+    h.Set(str("c"), &Integer{Value: 3})
+    h.Set(str("b"), &Integer{Value: 20})
+
+    if h.Len() != 3 {
+        t.Fatalf("expected 3 pairs, but got %d", h.Len())
+    }
+    if h.Inspect() != "{a: 1, b: 20, c: 3}" {
+        t.Errorf("colliding keys overwrote each other: %s", h.Inspect())
+    }
+
+    if !h.Delete(str("a")) {
+        t.Fatalf("failed to delete a colliding key")
+    }
+    if h.Delete(str("a")) {
+        t.Errorf("deleting a missing colliding key must return false")
+    }
+    if _, ok := h.Get(str("a")); ok {
+        t.Errorf("deleted key is still found")
+    }
+    for _, test := range []struct {
+        key string
+        expected string
+    }{
+        {"b", "20"},
+        {"c", "3"},
+    } {
+        pair, ok := h.Get(str(test.key))
+        if !ok || pair.Value.Inspect() != test.expected {
+            t.Errorf("Get(%s) returned %v, %t", test.key, pair.Value, ok)
+        }
+    }
+    if _, ok := h.Get(str("d")); ok {
+        t.Errorf("missing key with the same HashKey must not be found")
+    }
+
+    // 削除した後に同じバケットへ追加したキーは末尾に並ぶ
+    h.Set(str("a"), &Integer{Value: 4})
+    if h.Inspect() != "{b: 20, c: 3, a: 4}" {
+        t.Errorf("expected insertion order, but got %s", h.Inspect())
+    }
+
+    other := NewHash()
+    other.Set(str("a"), &Integer{Value: 4})
+    other.Set(str("c"), &Integer{Value: 3})
+    other.Set(str("b"), &Integer{Value: 20})
+    if !Equals(h, other) {
+        t.Errorf("hashes with the same colliding keys must be equal")
+    }
+}
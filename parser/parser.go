@@ -2,7 +2,8 @@ package parser
 
 import (
     "fmt"
-    "strconv"
+    "strings"
+    "math/big"
     "monkey_interpreter/ast"
     "monkey_interpreter/lexer"
     "monkey_interpreter/token"
@@ -11,10 +12,22 @@ import (
 type Parser struct {
     l *lexer.Lexer
     errors []string
+    warnings []string
 
     curToken token.Token
     peepToken token.Token
 
+    // 解析中のループの深さ. break, continueがループ外に書かれていないかの検査に用いる
+    loopDepth int
+
+    // matchのarmのpatternを読んでいる間はtrue. リテラルをpatternとして許す
+    inMatchPattern bool
+
+    // let, constで宣言された名前を、実行時の環境と同じ単位のスコープごとに記録する.
+    // 同じスコープでの再宣言とbuiltin関数の上書きを警告するために用いる
+    scopes []map[string]token.Token
+    builtins map[string]bool
+
     prefixParseFns map[token.TokenType]prefixParseFn
     infixParseFns map[token.TokenType]infixParseFn
 }
@@ -27,8 +40,15 @@ type (
 const (
     _ int = iota
     LOWEST
+    ASSIGN // = or +=, -=, ...
+    PIPE // |>
+    TERNARY // ? :
+    NULLISH // ??
+    LOGICAL_OR // ||
+    LOGICAL_AND // &&
     EQUALS // ==
-    LESSGREATER // > or <
+    LESSGREATER // >, <, >= or <=
+    RANGE // .. or ..<
     SUM // +
     PRODUCT // *
     PREFIX // -x or !x
@@ -37,23 +57,45 @@ const (
 )
 
 var precedences = map[token.TokenType]int {
+    token.ASSGIN: ASSIGN,
+    token.PLUS_ASSIGN: ASSIGN,
+    token.MINUS_ASSIGN: ASSIGN,
+    token.MUL_ASSIGN: ASSIGN,
+    token.DIV_ASSIGN: ASSIGN,
+    token.FLOORDIV_ASSIGN: ASSIGN,
+    token.MOD_ASSIGN: ASSIGN,
+    token.PIPE: PIPE,
+    token.QUESTION: TERNARY,
+    token.NULLISH: NULLISH,
+    token.OR: LOGICAL_OR,
+    token.AND: LOGICAL_AND,
     token.EQ: EQUALS,
     token.NQ: EQUALS,
     token.LT: LESSGREATER,
     token.GT: LESSGREATER,
+    token.LE: LESSGREATER,
+    token.GE: LESSGREATER,
+    token.RANGE: RANGE,
+    token.RANGE_EXCL: RANGE,
     token.PLUS: SUM,
     token.MINUS: SUM,
     token.MUL: PRODUCT,
     token.DIV: PRODUCT,
+    token.FLOORDIV: PRODUCT,
+    token.MOD: PRODUCT,
     // 中置記法としての`(`. 関数呼び出しに用いられる
     token.LPAREN: CALL,
     token.LBRACKET: INDEX,
+    token.OPTIONAL_CHAIN: INDEX,
 }
 
 func New(l *lexer.Lexer) *Parser {
     p := &Parser{
         l: l,
         errors: []string{},
+        warnings: []string{},
+        scopes: []map[string]token.Token{{}},
+        builtins: map[string]bool{},
     }
 
     p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
@@ -72,22 +114,43 @@ func New(l *lexer.Lexer) *Parser {
     p.registerPrefix(token.MINUS, p.parsePrefixExpression)
     p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
     p.registerPrefix(token.IF, p.parseIfExpression)
+    p.registerPrefix(token.MATCH, p.parseMatchExpression)
+    p.registerPrefix(token.TRY, p.parseTryExpression)
     p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
     p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
     p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+    p.registerInfix(token.ASSGIN, p.parseAssignExpression)
+    p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.MUL_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.DIV_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.FLOORDIV_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.MOD_ASSIGN, p.parseAssignExpression)
+    p.registerInfix(token.PIPE, p.parsePipeExpression)
+    p.registerInfix(token.QUESTION, p.parseTernaryExpression)
+    p.registerInfix(token.NULLISH, p.parseInfixExpression)
+    p.registerInfix(token.AND, p.parseInfixExpression)
+    p.registerInfix(token.OR, p.parseInfixExpression)
     p.registerInfix(token.EQ, p.parseInfixExpression)
     p.registerInfix(token.NQ, p.parseInfixExpression)
     p.registerInfix(token.LT, p.parseInfixExpression)
     p.registerInfix(token.GT, p.parseInfixExpression)
+    p.registerInfix(token.LE, p.parseInfixExpression)
+    p.registerInfix(token.GE, p.parseInfixExpression)
+    p.registerInfix(token.RANGE, p.parseInfixExpression)
+    p.registerInfix(token.RANGE_EXCL, p.parseInfixExpression)
     p.registerInfix(token.PLUS, p.parseInfixExpression)
     p.registerInfix(token.MINUS, p.parseInfixExpression)
     p.registerInfix(token.MUL, p.parseInfixExpression)
     p.registerInfix(token.DIV, p.parseInfixExpression)
+    p.registerInfix(token.FLOORDIV, p.parseInfixExpression)
+    p.registerInfix(token.MOD, p.parseInfixExpression)
     // 関数呼び出しは add(2, 5); となるが
     // addはprefixのIdentifierとしてparseされ、
     // `(`が来て、引数2へと続く。つまり`(`を中置記号とも見なせる
     p.registerInfix(token.LPAREN, p.parseFunctionCall)
     p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+    p.registerInfix(token.OPTIONAL_CHAIN, p.parseOptionalIndexExpression)
 
     p.nextToken()
     p.nextToken()
@@ -99,6 +162,71 @@ func (p *Parser) Errors() []string {
     return p.errors
 }
 
+// 実行に支障はないが、誤りの可能性がある箇所への警告
+func (p *Parser) Warnings() []string {
+    return p.warnings
+}
+
+// let/constでの上書きを警告するbuiltin関数の名前を登録する
+func (p *Parser) SetBuiltins(names []string) {
+    for _, name := range names {
+        p.builtins[name] = true
+    }
+}
+
+func (p *Parser) warn(tok token.Token, format string, a ...interface{}) {
+    msg := fmt.Sprintf("%d:%d: ", tok.Line, tok.Column) + fmt.Sprintf(format, a...)
+    p.warnings = append(p.warnings, msg)
+}
+
+func (p *Parser) openScope() {
+    p.scopes = append(p.scopes, map[string]token.Token{})
+}
+
+func (p *Parser) closeScope() {
+    p.scopes = p.scopes[:len(p.scopes) - 1]
+}
+
+// 現在のスコープに名前を宣言する. 同じスコープでの再宣言を警告する.
+// builtin関数の上書きは、引数やループ変数などでは意図的なことが多いので、let/constの場合(isLet)のみ警告する
+func (p *Parser) declare(id *ast.Identifier, isLet bool) {
+    scope := p.scopes[len(p.scopes) - 1]
+    if id.Value == "_" {
+        return
+    }
+
+    if prev, ok := scope[id.Value]; ok {
+        p.warn(id.Token, "%s is already declared in this scope at %d:%d", id.Value, prev.Line, prev.Column)
+    } else if isLet && p.builtins[id.Value] {
+        p.warn(id.Token, "%s shadows a builtin function", id.Value)
+    }
+
+    scope[id.Value] = id.Token
+}
+
+// pattern中で束縛される全ての名前を宣言する
+func (p *Parser) declarePattern(pat ast.Pattern, isLet bool) {
+    switch pat := pat.(type) {
+    case *ast.Identifier:
+        p.declare(pat, isLet)
+    case *ast.RestPattern:
+        p.declare(pat.Name, isLet)
+    case *ast.DefaultPattern:
+        p.declarePattern(pat.Target, isLet)
+    case *ast.ArrayPattern:
+        for _, elem := range pat.Elems {
+            p.declarePattern(elem, isLet)
+        }
+    case *ast.HashPattern:
+        for _, entry := range pat.Entries {
+            p.declarePattern(entry.Value, isLet)
+        }
+        if pat.Rest != nil {
+            p.declarePattern(pat.Rest, isLet)
+        }
+    }
+}
+
 func (p *Parser) peepError(t token.TokenType) {
     msg := fmt.Sprintf("expected next token to be %s, but got %s instead",
     t, p.peepToken.Type)
@@ -136,10 +264,22 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 func (p *Parser) parseStatement() ast.Statement {
     switch p.curToken.Type {
-    case token.LET:
-        return p.parseLetStatement()
+    case token.LET, token.CONST:
+        // 失敗時のnilを型付きのnilとしてast.Statementに詰めないようにする
+        if stmt := p.parseLetStatement(); stmt != nil {
+            return stmt
+        }
+        return nil
     case token.RETURN:
         return p.parseReturnStatement()
+    case token.THROW:
+        return p.parseThrowStatement()
+    case token.WHILE:
+        return p.parseWhileStatement()
+    case token.FOR:
+        return p.parseForStatement()
+    case token.BREAK, token.CONTINUE:
+        return p.parseLoopControlStatement()
     default:
         return p.parseExpressionStatement()
     }
@@ -147,11 +287,19 @@ func (p *Parser) parseStatement() ast.Statement {
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
     stmt := &ast.LetStatement{Token: p.curToken}
-    if !p.expectPeep(token.IDENT) {
-        return nil
-    }
 
-    stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    if p.peepTokenIs(token.LBRACKET) || p.peepTokenIs(token.LBRACE) {
+        p.nextToken()
+        stmt.Pattern = p.parsePattern()
+        if stmt.Pattern == nil {
+            return nil
+        }
+    } else {
+        if !p.expectPeep(token.IDENT) {
+            return nil
+        }
+        stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    }
 
     if !p.expectPeep(token.ASSGIN) {
         return nil
@@ -164,6 +312,13 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
         p.nextToken()
     }
 
+    // 右辺では外側の同名の束縛を参照できるよう、宣言は右辺の後に行う
+    if stmt.Pattern != nil {
+        p.declarePattern(stmt.Pattern, true)
+    } else {
+        p.declare(stmt.Name, true)
+    }
+
     return stmt
 }
 
@@ -179,6 +334,99 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
     return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+    stmt := &ast.ThrowStatement{Token: p.curToken}
+
+    p.nextToken()
+    stmt.Value = p.parseExpression(LOWEST)
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+    return stmt
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+    ws := &ast.WhileStatement{Token: p.curToken}
+
+    if !p.expectPeep(token.LPAREN) {
+        return nil
+    }
+    ws.Cond = p.parseExpression(LOWEST)
+
+    if !p.expectPeep(token.LBRACE) {
+        return nil
+    }
+    p.openScope()
+    ws.Body = p.parseLoopBody()
+    p.closeScope()
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+    return ws
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+    fs := &ast.ForStatement{Token: p.curToken}
+
+    if !p.expectPeep(token.LPAREN) {
+        return nil
+    }
+    if !p.expectPeep(token.IDENT) {
+        return nil
+    }
+    fs.Var = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    if !p.expectPeep(token.IN) {
+        return nil
+    }
+    p.nextToken()
+    fs.Iterable = p.parseExpression(LOWEST)
+
+    if !p.expectPeep(token.RPAREN) {
+        return nil
+    }
+    if !p.expectPeep(token.LBRACE) {
+        return nil
+    }
+
+    p.openScope()
+    p.declare(fs.Var, false)
+    fs.Body = p.parseLoopBody()
+    p.closeScope()
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+    return fs
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+    p.loopDepth++
+    body := p.parseBlockStatement()
+    p.loopDepth--
+    return body
+}
+
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+    tok := p.curToken
+
+    if p.peepTokenIs(token.SEMICOLON) {
+        p.nextToken()
+    }
+
+    if p.loopDepth == 0 {
+        p.errors = append(p.errors, fmt.Sprintf("%s outside of loop", tok.Literal))
+        return nil
+    }
+
+    if tok.Type == token.BREAK {
+        return &ast.BreakStatement{Token: tok}
+    }
+    return &ast.ContinueStatement{Token: tok}
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
     stmt := &ast.ExpressionStatement{Token: p.curToken}
     stmt.Expression = p.parseExpression(LOWEST)
@@ -238,13 +486,74 @@ func (p *Parser) parseIdentifier() ast.Expression {
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
-    val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+    val, err := parseInteger(p.curToken.Literal)
     if err != nil {
-        msg := fmt.Sprintf("cannot parse %q as integer", p.curToken.Literal)
-        p.errors = append(p.errors, msg)
+        p.errors = append(p.errors, err.Error())
         return nil
     }
-    return &ast.IntegerLiteral{Token: p.curToken, Value: val}
+    if !val.IsInt64() {
+        return &ast.IntegerLiteral{Token: p.curToken, Big: val}
+    }
+    return &ast.IntegerLiteral{Token: p.curToken, Value: val.Int64()}
+}
+
+// 整数リテラルを解釈する。接頭辞0x, 0o, 0bで基数を切り替え、
+// `_`は桁と桁の間にのみ置ける
+func parseInteger(lit string) (*big.Int, error) {
+    base, name, digits := 10, "decimal", lit
+    if len(lit) >= 2 && lit[0] == '0' {
+        switch lit[1] {
+        case 'x', 'X':
+            base, name = 16, "hexadecimal"
+        case 'o', 'O':
+            base, name = 8, "octal"
+        case 'b', 'B':
+            base, name = 2, "binary"
+        }
+        if base != 10 {
+            digits = lit[2:]
+        }
+    }
+
+    if len(digits) == 0 {
+        return nil, fmt.Errorf("%s literal %q has no digits", name, lit)
+    }
+
+    for i := 0; i < len(digits); i++ {
+        c := digits[i]
+        if c == '_' {
+            if i == 0 || i == len(digits) - 1 || digits[i+1] == '_' {
+                return nil, fmt.Errorf("'_' must separate successive digits in %q", lit)
+            }
+            continue
+        }
+        if digitVal(c) >= base {
+            return nil, fmt.Errorf("invalid digit %q in %s literal %q", c, name, lit)
+        }
+    }
+    // 以前は`010`を8進数として読んでいたので、意味が黙って変わらないようエラーにする
+    if base == 10 && len(digits) > 1 && digits[0] == '0' {
+        return nil, fmt.Errorf("leading zeros are not allowed in decimal literal %q; use 0o for octal", lit)
+    }
+
+    // int64に収まらない値はBigIntegerとして扱う
+    val, ok := new(big.Int).SetString(strings.ReplaceAll(digits, "_", ""), base)
+    if !ok {
+        return nil, fmt.Errorf("cannot parse %q as integer", lit)
+    }
+    return val, nil
+}
+
+func digitVal(c byte) int {
+    switch {
+    case '0' <= c && c <= '9':
+        return int(c - '0')
+    case 'a' <= c && c <= 'z':
+        return int(c - 'a' + 10)
+    case 'A' <= c && c <= 'Z':
+        return int(c - 'A' + 10)
+    }
+    return 36
 }
 
 func (p *Parser) parseStringLiteral() ast.Expression {
@@ -299,82 +608,502 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 }
 
-func (p *Parser) parseFunctionLiteralParams() []*ast.Identifier {
+func (p *Parser) parseTryExpression() ast.Expression {
+    te := &ast.TryExpression{Token: p.curToken}
 
-    params := []*ast.Identifier{}
-    p.nextToken()
-    if p.curTokenIs(token.RPAREN) {
+    if !p.expectPeep(token.LBRACE) {
+        return nil
+    }
+    te.Body = p.parseBlockStatement()
+
+    if p.peepTokenIs(token.CATCH) {
+        p.nextToken()
+        if !p.expectPeep(token.LPAREN) {
+            return nil
+        }
         p.nextToken()
-        return params
+        te.CatchParam = p.parsePattern()
+        if te.CatchParam == nil {
+            return nil
+        }
+        if !p.expectPeep(token.RPAREN) {
+            return nil
+        }
+        if !p.expectPeep(token.LBRACE) {
+            return nil
+        }
+
+        // catchの引数はcatch節の中からのみ見える
+        p.openScope()
+        p.declarePattern(te.CatchParam, false)
+        te.Catch = p.parseBlockStatement()
+        p.closeScope()
     }
 
-    for !p.curTokenIs(token.RPAREN) && !p.curTokenIs(token.EOF) {
-        id, ok := p.parseIdentifier().(*ast.Identifier)
-        if !ok {
-            p.errors = append(p.errors, "type assertion to (*ast.identifier) error")
+    if p.peepTokenIs(token.FINALLY) {
+        p.nextToken()
+        if !p.expectPeep(token.LBRACE) {
+            return nil
         }
-        params = append(params, id)
+        te.Finally = p.parseBlockStatement()
+    }
 
-        if p.peepTokenIs(token.COMMA) {
+    if te.Catch == nil && te.Finally == nil {
+        p.errors = append(p.errors, "try requires catch or finally")
+        return nil
+    }
+    return te
+}
+
+// 分割代入の左辺を読む. curTokenはpatternの先頭
+func (p *Parser) parsePattern() ast.Pattern {
+    switch p.curToken.Type {
+    case token.IDENT:
+        return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    case token.LBRACKET:
+        ap := &ast.ArrayPattern{Token: p.curToken}
+        ap.Elems = p.parsePatternList(token.RBRACKET, false)
+        if ap.Elems == nil {
+            return nil
+        }
+        return ap
+    case token.LBRACE:
+        return p.parseHashPattern()
+    case token.INT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+        if p.inMatchPattern {
+            return p.parseLiteralPattern()
+        }
+    }
+
+    msg := fmt.Sprintf("unexpected %s in pattern", p.curToken.Literal)
+    p.errors = append(p.errors, msg)
+    return nil
+}
+
+// 整数(負数を含む), 文字列, 真偽値のリテラルをpatternとして読む
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+    lp := &ast.LiteralPattern{Token: p.curToken}
+
+    if p.curTokenIs(token.MINUS) {
+        if !p.expectPeep(token.INT) {
+            return nil
+        }
+        pe := &ast.PrefixExpression{Token: lp.Token, Operator: "-"}
+        pe.Right = p.parseIntegerLiteral()
+        if pe.Right == nil {
+            return nil
+        }
+        lp.Value = pe
+        return lp
+    }
+
+    lp.Value = p.prefixParseFns[p.curToken.Type]()
+    if lp.Value == nil {
+        return nil
+    }
+    return lp
+}
+
+// <pattern>, <pattern>, ...<identifier> をendまで読む. allowDefaultがtrueなら
+// 関数の引数として<pattern> = <expression>の形のデフォルト値を書ける.
+// curTokenは開き括弧で、読み終わるとendの位置にいる. 失敗した場合はnilを返す
+func (p *Parser) parsePatternList(end token.TokenType, allowDefault bool) []ast.Pattern {
+    list := []ast.Pattern{}
+    hasDefault := false
+
+    for !p.peepTokenIs(end) {
+        p.nextToken()
+
+        if p.curTokenIs(token.ELLIPSIS) {
+            rest := p.parseRestPattern()
+            if rest == nil {
+                return nil
+            }
+            // `...`は最後の要素でなければならないので、ここで終端を期待する
+            list = append(list, rest)
+            break
+        }
+
+        pat := p.parsePattern()
+        if pat == nil {
+            return nil
+        }
+
+        if allowDefault && p.peepTokenIs(token.ASSGIN) {
+            p.nextToken()
+            dp := &ast.DefaultPattern{Token: p.curToken, Target: pat}
+            p.nextToken()
+            dp.Default = p.parseExpression(LOWEST)
+            if dp.Default == nil {
+                return nil
+            }
+            pat = dp
+            hasDefault = true
+        } else if hasDefault {
+            // 名前付き引数を使わずにデフォルト値を省略できるよう、デフォルト値の後ろには置かせない
+            msg := fmt.Sprintf("parameter %s without default follows parameter with default", pat.String())
+            p.errors = append(p.errors, msg)
+            return nil
+        }
+        list = append(list, pat)
+
+        if !p.peepTokenIs(token.COMMA) {
+            break
+        }
+        p.nextToken()
+    }
+
+    if !p.expectPeep(end) {
+        return nil
+    }
+    return list
+}
+
+func (p *Parser) parseRestPattern() *ast.RestPattern {
+    rp := &ast.RestPattern{Token: p.curToken}
+    if !p.expectPeep(token.IDENT) {
+        return nil
+    }
+    rp.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+    return rp
+}
+
+func (p *Parser) parseHashPattern() ast.Pattern {
+    hp := &ast.HashPattern{Token: p.curToken}
+
+    for !p.peepTokenIs(token.RBRACE) {
+        p.nextToken()
+
+        if p.curTokenIs(token.ELLIPSIS) {
+            hp.Rest = p.parseRestPattern()
+            if hp.Rest == nil {
+                return nil
+            }
+            break
+        }
+
+        if !p.curTokenIs(token.IDENT) {
+            msg := fmt.Sprintf("expected identifier as hash pattern key, but got %s instead", p.curToken.Type)
+            p.errors = append(p.errors, msg)
+            return nil
+        }
+        key := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+        entry := &ast.HashPatternEntry{Key: key, Value: key}
+
+        if p.peepTokenIs(token.COLON) {
+            p.nextToken()
             p.nextToken()
+            entry.Value = p.parsePattern()
+            if entry.Value == nil {
+                return nil
+            }
+        }
+        hp.Entries = append(hp.Entries, entry)
+
+        if !p.peepTokenIs(token.COMMA) {
+            break
         }
         p.nextToken()
     }
 
+    if !p.expectPeep(token.RBRACE) {
+        return nil
+    }
+    return hp
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+    me := &ast.MatchExpression{Token: p.curToken}
+
+    if !p.expectPeep(token.LPAREN) {
+        return nil
+    }
     p.nextToken()
+    me.Subject = p.parseExpression(LOWEST)
+    if !p.expectPeep(token.RPAREN) {
+        return nil
+    }
+    if !p.expectPeep(token.LBRACE) {
+        return nil
+    }
+
+    // ガードなしの束縛patternは必ずマッチするので、それ以降のarmには到達しない
+    var catchAll *ast.MatchArm
+    for !p.peepTokenIs(token.RBRACE) {
+        p.nextToken()
 
-    return params
+        arm := p.parseMatchArm()
+        if arm == nil {
+            return nil
+        }
+        if catchAll != nil {
+            p.warn(arm.Token, "unreachable match arm: %s already matches every value", catchAll.Pattern.String())
+        } else if _, ok := arm.Pattern.(*ast.Identifier); ok && arm.Guard == nil {
+            catchAll = arm
+        }
+        me.Arms = append(me.Arms, arm)
+
+        if !p.peepTokenIs(token.COMMA) {
+            break
+        }
+        p.nextToken()
+    }
+
+    if !p.expectPeep(token.RBRACE) {
+        return nil
+    }
+    return me
+}
+
+// curTokenはarmのpatternの先頭
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+    arm := &ast.MatchArm{Token: p.curToken}
+
+    p.inMatchPattern = true
+    arm.Pattern = p.parsePattern()
+    p.inMatchPattern = false
+    if arm.Pattern == nil {
+        return nil
+    }
+
+    // patternで束縛した名前はguardとbodyからのみ見える
+    p.openScope()
+    defer p.closeScope()
+    p.declarePattern(arm.Pattern, false)
+
+    if p.peepTokenIs(token.IF) {
+        p.nextToken()
+        p.nextToken()
+        arm.Guard = p.parseExpression(LOWEST)
+        if arm.Guard == nil {
+            return nil
+        }
+    }
+
+    if !p.expectPeep(token.ARROW) {
+        return nil
+    }
+    p.nextToken()
+    arm.Body = p.parseExpression(LOWEST)
+    if arm.Body == nil {
+        return nil
+    }
+
+    return arm
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
     fl := &ast.FunctionLiteral{Token: p.curToken}
-    p.expectPeep(token.LPAREN)
+    if !p.expectPeep(token.LPAREN) {
+        return nil
+    }
 
-    fl.Params = p.parseFunctionLiteralParams()
+    fl.Params = p.parsePatternList(token.RPAREN, true)
+    if fl.Params == nil {
+        return nil
+    }
 
-    if p.peepTokenIs(token.RBRACE) {
-        p.nextToken()
-        return fl
+    if !p.expectPeep(token.LBRACE) {
+        return nil
+    }
+
+    p.openScope()
+    defer p.closeScope()
+    for _, param := range fl.Params {
+        p.declarePattern(param, false)
     }
+
+    // 関数本体は外側のループとは無関係なので、ループの深さを一旦リセットする
+    depth := p.loopDepth
+    p.loopDepth = 0
     fl.Body = p.parseBlockStatement()
+    p.loopDepth = depth
 
     return fl
 }
 
 func (p *Parser) parseFunctionCall(f ast.Expression) ast.Expression {
     fc := &ast.FunctionCall{Token: p.curToken, Func: f}
-    fc.Args = p.parseExpressionList(token.RPAREN)
+    fc.Args = p.parseCallArguments()
     return fc
 }
 
+// 引数を`)`まで読む. <identifier>: <expression>の形は名前付き引数となり、
+// 位置で渡す引数より後ろにしか置けない
+func (p *Parser) parseCallArguments() []ast.Expression {
+    args := p.parseExpressionList(token.RPAREN)
+
+    named := false
+    for _, arg := range args {
+        if _, ok := arg.(*ast.NamedArgument); ok {
+            named = true
+        } else if named {
+            p.errors = append(p.errors, "positional argument follows named argument")
+            return nil
+        }
+    }
+
+    return args
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
     array := &ast.ArrayLiteral{Token: p.curToken}
-    array.Elems = p.parseExpressionList(token.RBRACKET)
+    if p.peepTokenIs(token.RBRACKET) {
+        array.Elems = p.parseExpressionList(token.RBRACKET)
+        return array
+    }
+
+    // 最初の要素の後ろにforが続けば内包表記
+    p.nextToken()
+    first := p.parseExpression(LOWEST)
+    if p.peepTokenIs(token.FOR) {
+        ac := &ast.ArrayComprehension{Token: array.Token, Elem: first}
+        p.nextToken()
+        ac.Clause = p.parseComprehensionClause()
+        if first == nil || ac.Clause == nil || !p.expectPeep(token.RBRACKET) {
+            return nil
+        }
+        return ac
+    }
+
+    if first != nil {
+        array.Elems = append(array.Elems, first)
+    }
+    if p.peepTokenIs(token.COMMA) {
+        p.nextToken()
+    }
+    array.Elems = append(array.Elems, p.parseExpressionList(token.RBRACKET)...)
     return array
 }
 
+// 内包表記のfor節を読む. curTokenは`for`
+func (p *Parser) parseComprehensionClause() *ast.ComprehensionClause {
+    cc := &ast.ComprehensionClause{Token: p.curToken}
+
+    p.nextToken()
+    cc.Value = p.parsePattern()
+    if cc.Value == nil {
+        return nil
+    }
+    if p.peepTokenIs(token.COMMA) {
+        p.nextToken()
+        p.nextToken()
+        cc.Key = cc.Value
+        cc.Value = p.parsePattern()
+        if cc.Value == nil {
+            return nil
+        }
+    }
+
+    if !p.expectPeep(token.IN) {
+        return nil
+    }
+    p.nextToken()
+    cc.Iterable = p.parseExpression(LOWEST)
+    if cc.Iterable == nil {
+        return nil
+    }
+
+    // ループ変数は条件式からのみ見える. 要素の式は既に読んであるので、ここでスコープを開く
+    p.openScope()
+    defer p.closeScope()
+    if cc.Key != nil {
+        p.declarePattern(cc.Key, false)
+    }
+    p.declarePattern(cc.Value, false)
+
+    if p.peepTokenIs(token.IF) {
+        p.nextToken()
+        p.nextToken()
+        cc.Cond = p.parseExpression(LOWEST)
+        if cc.Cond == nil {
+            return nil
+        }
+    }
+
+    return cc
+}
+
+// <expression>[<index>] または <expression>[<start>:<end>:<step>]. curTokenは`[`
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-    ie := &ast.IndexExpression{Token: p.curToken, Left: left}
+    tok := p.curToken
     p.nextToken()
-    ie.Index = p.parseExpression(LOWEST)
 
-    p.expectPeep(token.RBRACKET)
+    var start ast.Expression
+    if !p.curTokenIs(token.COLON) {
+        start = p.parseExpression(LOWEST)
+        if !p.peepTokenIs(token.COLON) {
+            p.expectPeep(token.RBRACKET)
+            return &ast.IndexExpression{Token: tok, Left: left, Index: start}
+        }
+        p.nextToken()
+    }
+
+    // ここでcurTokenは1つ目の`:`
+    se := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+    if !p.peepTokenIs(token.COLON) && !p.peepTokenIs(token.RBRACKET) {
+        p.nextToken()
+        se.End = p.parseExpression(LOWEST)
+    }
+    if p.peepTokenIs(token.COLON) {
+        p.nextToken()
+        if !p.peepTokenIs(token.RBRACKET) {
+            p.nextToken()
+            se.Step = p.parseExpression(LOWEST)
+        }
+    }
 
-    return ie
+    if !p.expectPeep(token.RBRACKET) {
+        return nil
+    }
+    return se
+}
+
+// <expression>?.[<expression>] または <expression>?.[<start>:<end>:<step>]. curTokenは`?.`
+func (p *Parser) parseOptionalIndexExpression(left ast.Expression) ast.Expression {
+    if !p.expectPeep(token.LBRACKET) {
+        return nil
+    }
+
+    switch exp := p.parseIndexExpression(left).(type) {
+    case *ast.IndexExpression:
+        exp.Optional = true
+        return exp
+    case *ast.SliceExpression:
+        exp.Optional = true
+        return exp
+    }
+    return nil
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
     hl := &ast.HashLiteral{Token: p.curToken}
     p.nextToken()
 
-    pairs := map[ast.Expression]ast.Expression{}
+    pairs := []ast.HashLiteralPair{}
 
-    for !p.curTokenIs(token.RBRACE) {
+    for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
         key := p.parseExpression(LOWEST)
+        // 最初の要素の後ろに`:`がなければ集合
+        if len(pairs) == 0 && !p.peepTokenIs(token.COLON) {
+            return p.parseSetLiteral(hl.Token, key)
+        }
         p.expectPeep(token.COLON)
         p.nextToken()
         val := p.parseExpression(LOWEST)
-        pairs[key] = val
+
+        // 最初のペアの後ろにforが続けば内包表記
+        if len(pairs) == 0 && p.peepTokenIs(token.FOR) {
+            hc := &ast.HashComprehension{Token: hl.Token, Key: key, Value: val}
+            p.nextToken()
+            hc.Clause = p.parseComprehensionClause()
+            if key == nil || val == nil || hc.Clause == nil || !p.expectPeep(token.RBRACE) {
+                return nil
+            }
+            return hc
+        }
+
+        pairs = append(pairs, ast.HashLiteralPair{Key: key, Value: val})
         if p.peepTokenIs(token.COMMA) {
             p.nextToken()
         }
@@ -386,12 +1115,45 @@ func (p *Parser) parseHashLiteral() ast.Expression {
     return hl
 }
 
+// 最初の要素firstは読み終えており、curTokenはその最後のトークン
+func (p *Parser) parseSetLiteral(tok token.Token, first ast.Expression) ast.Expression {
+    sl := &ast.SetLiteral{Token: tok}
+    if first == nil {
+        return nil
+    }
+    sl.Elems = append(sl.Elems, first)
+
+    for p.peepTokenIs(token.COMMA) {
+        p.nextToken()
+        // 末尾のカンマは許す
+        if p.peepTokenIs(token.RBRACE) {
+            break
+        }
+        p.nextToken()
+        elem := p.parseExpression(LOWEST)
+        if elem == nil {
+            return nil
+        }
+        sl.Elems = append(sl.Elems, elem)
+    }
+
+    if !p.expectPeep(token.RBRACE) {
+        return nil
+    }
+    return sl
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
     var elems []ast.Expression
     p.nextToken()
 
     for !p.curTokenIs(end) && !p.curTokenIs(token.EOF) {
-        elem := p.parseExpression(LOWEST)
+        var elem ast.Expression
+        if end == token.RPAREN && p.curTokenIs(token.IDENT) && p.peepTokenIs(token.COLON) {
+            elem = p.parseNamedArgument()
+        } else {
+            elem = p.parseExpression(LOWEST)
+        }
         if elem != nil {
             elems = append(elems, elem)
         }
@@ -403,6 +1165,17 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
     return elems
 }
 
+func (p *Parser) parseNamedArgument() ast.Expression {
+    na := &ast.NamedArgument{Token: p.curToken}
+    na.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+    p.nextToken()
+    p.nextToken()
+    na.Value = p.parseExpression(LOWEST)
+
+    return na
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
     pe := &ast.PrefixExpression{Token: p.curToken, Operator: p.curToken.Literal}
     p.nextToken()
@@ -426,6 +1199,70 @@ func (p *Parser) parseInfixExpression(leftExp ast.Expression) ast.Expression {
     return ie
 }
 
+// x |> f(a)をf(x, a)に、x |> fをf(x)に置き換えた関数呼び出しとして読む
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+    tok := p.curToken
+
+    p.nextToken()
+    right := p.parseExpression(PIPE)
+
+    switch right := right.(type) {
+    case nil:
+        return nil
+    case *ast.FunctionCall:
+        args := append([]ast.Expression{left}, right.Args...)
+        return &ast.FunctionCall{Token: right.Token, Func: right.Func, Args: args}
+    default:
+        return &ast.FunctionCall{Token: tok, Func: right, Args: []ast.Expression{left}}
+    }
+}
+
+func (p *Parser) parseTernaryExpression(cond ast.Expression) ast.Expression {
+    te := &ast.TernaryExpression{Token: p.curToken, Cond: cond}
+
+    p.nextToken()
+    te.Cons = p.parseExpression(LOWEST)
+
+    if !p.expectPeep(token.COLON) {
+        return nil
+    }
+    p.nextToken()
+
+    // a ? b : c ? d : eをa ? b : (c ? d : e)と解釈するため、1つ低い優先順位でparseする
+    te.Alt = p.parseExpression(TERNARY - 1)
+
+    return te
+}
+
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+    ae := &ast.AssignExpression{
+        Token: p.curToken,
+        Target: target,
+        Operator: p.curToken.Literal,
+    }
+
+    switch target := target.(type) {
+    case *ast.Identifier:
+    case *ast.IndexExpression:
+        if target.Optional {
+            p.errors = append(p.errors, fmt.Sprintf("cannot assign to %s", target.String()))
+            return nil
+        }
+    case nil:
+        return nil
+    default:
+        p.errors = append(p.errors, fmt.Sprintf("cannot assign to %s", target.String()))
+        return nil
+    }
+
+    p.nextToken()
+
+    // a = b = cをa = (b = c)と解釈するため、右辺は1つ低い優先順位でparseする
+    ae.Value = p.parseExpression(ASSIGN - 1)
+
+    return ae
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
     return p.curToken.Type == t
 }
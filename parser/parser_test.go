@@ -16,6 +16,7 @@ func TestLetStatements(t *testing.T) {
         {"let x = 5;", "x", 5},
         {"let y = true;", "y", true},
         {"let foo = y;", "foo", "y"},
+        {"const z = 5;", "z", 5},
     }
 
     for _, test := range tests {
@@ -67,7 +68,7 @@ func TestReturnStatements(t *testing.T) {
 }
 
 func testLetStatement(t *testing.T, stmt ast.Statement, name string) bool {
-    if stmt.TokenLiteral() != "let" {
+    if stmt.TokenLiteral() != "let" && stmt.TokenLiteral() != "const" {
         return false
     }
 
@@ -100,6 +101,215 @@ func checkParserErrors(t *testing.T, p *Parser) {
     t.FailNow()
 }
 
+func TestDestructuringPatterns(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let [a, b, ...rest] = arr;", "let [a, b, ...rest] = arr;"},
+        {"let [a, [b, _],] = arr;", "let [a, [b, _]] = arr;"},
+        {"const {name, age: [x, y], ...r} = person;", "const {name, age: [x, y], ...r} = person;"},
+        {"let {} = h;", "let {} = h;"},
+        {"fn([a, b], {c}, d) { a }", "fn([a, b], {c}, d)a"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, program.String())
+        }
+    }
+}
+
+func TestDestructuringPatternErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedMsg string
+    }{
+        {"let [1] = a;", "unexpected 1 in pattern"},
+        {"let [...a, b] = c;", "expected next token to be ], but got , instead"},
+        {"let {1} = a;", "expected identifier as hash pattern key, but got INT instead"},
+        {"let [a = 1] = b;", "expected next token to be ], but got = instead"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expectedMsg {
+            t.Errorf("%s: expected error %q, but got %q", test.input, test.expectedMsg, errors)
+        }
+    }
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"match (x) { 0 => 1, -1 => 2, _ => 3 }", "match (x) { 0 => 1, (-1) => 2, _ => 3 }"},
+        {`match (x) { "a" => true, true => false, }`, `match (x) { a => true, true => false }`},
+        {"match (p) { [a, ...r] if a > 0 => a + 1, {k: [1, v]} => v }", "match (p) { [a, ...r] if (a > 0) => (a + 1), {k: [1, v]} => v }"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, program.String())
+        }
+    }
+
+    l := lexer.New("let [1, x] = a;")
+    p := New(l)
+    p.ParseProgram()
+    if errors := p.Errors(); len(errors) == 0 || errors[0] != "unexpected 1 in pattern" {
+        t.Errorf("literal pattern outside of match must be rejected, got %q", errors)
+    }
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"try { f() } catch (e) { 1 }", "tryf()catch(e)1"},
+        {"try { f() } finally { g() }", "tryf()finallyg()"},
+        {"try { f() } catch ({message}) { message } finally { g() }", "tryf()catch({message})messagefinallyg()"},
+        {`throw "oops";`, "throw oops;"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, program.String())
+        }
+    }
+
+    l := lexer.New("try { f() }")
+    p := New(l)
+    p.ParseProgram()
+    if errors := p.Errors(); len(errors) == 0 || errors[0] != "try requires catch or finally" {
+        t.Errorf("expected error for try without catch or finally, got %q", errors)
+    }
+}
+
+func TestConditionalOperatorErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedMsg string
+    }{
+        {"a ? b", "expected next token to be :, but got EOF instead"},
+        {"a?.b", "expected next token to be [, but got IDENT instead"},
+        {"a?.[b] = 1", "cannot assign to (a?.[b])"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expectedMsg {
+            t.Errorf("%s: expected error %q, but got %q", test.input, test.expectedMsg, errors)
+        }
+    }
+}
+
+func TestComprehensionParsing(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"[x * 2 for x in xs if x > 0]", "[(x * 2) for x in xs if (x > 0)]"},
+        {"[i for i in 1..10]", "[i for i in (1 .. 10)]"},
+        {"[a + b for [a, b] in pairs]", "[(a + b) for [a, b] in pairs]"},
+        {"{k: v * 2 for k, v in h}", "{k:(v * 2) for k, v in h}"},
+        {"[1, 2, 3]", "[1, 2, 3]"},
+        {"[1, 2,]", "[1, 2]"},
+        {"[]", "[]"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, program.String())
+        }
+    }
+
+    l := lexer.New("[x for x of xs]")
+    p := New(l)
+    p.ParseProgram()
+    if errors := p.Errors(); len(errors) == 0 || errors[0] != "expected next token to be IN, but got IDENT instead" {
+        t.Errorf("expected error for comprehension without in, got %q", errors)
+    }
+}
+
+func TestDeclarationWarnings(t *testing.T) {
+    tests := []struct {
+        input string
+        expected []string
+    }{
+        {"let x = 1; let y = 2;", []string{}},
+        {"let x = 1;\nlet x = 2;", []string{"2:5: x is already declared in this scope at 1:5"}},
+        {"const x = 1; let x = 2;", []string{"1:18: x is already declared in this scope at 1:7"}},
+        {"let x = 1; if (true) { let x = 2; }", []string{"1:28: x is already declared in this scope at 1:5"}},
+        {"let x = 1; let f = fn() { let x = 2; };", []string{}},
+        {"let f = fn(x) { let x = 2; };", []string{"1:21: x is already declared in this scope at 1:12"}},
+        {"let x = 1; while (true) { let x = 2; }", []string{}},
+        {"for (x in xs) { let x = 2; }", []string{"1:21: x is already declared in this scope at 1:6"}},
+        {"let len = fn(x) { 0 };", []string{"1:5: len shadows a builtin function"}},
+        {"let f = fn() { const puts = 1; };", []string{"1:22: puts shadows a builtin function"}},
+        {"let [len, {a: puts}] = v;", []string{"1:6: len shadows a builtin function", "1:15: puts shadows a builtin function"}},
+        {"let f = fn(len, ...puts) { len };", []string{}},
+        {"for (len in xs) { len }", []string{}},
+        {"try { 1 } catch (puts) { puts }", []string{}},
+        {"match (v) { [len] => len, _ => 0 }", []string{}},
+        {"[len for len in xs]", []string{}},
+        {"let [x, {y: x}] = a;", []string{"1:13: x is already declared in this scope at 1:6"}},
+        {"let [_, _] = a;", []string{}},
+        {"match (v) { 1 => 2, n => n, _ => 0 }", []string{"1:29: unreachable match arm: n already matches every value"}},
+        {"match (v) { n if n > 0 => n, _ => 0 }", []string{}},
+        {"let x = 1; match (v) { [x] => x, x => x }", []string{}},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.SetBuiltins([]string{"len", "puts"})
+        p.ParseProgram()
+        checkParserErrors(t, p)
+
+        warnings := p.Warnings()
+        if len(warnings) != len(test.expected) {
+            t.Errorf("%s: expected warnings %q, but got %q", test.input, test.expected, warnings)
+            continue
+        }
+        for i, msg := range test.expected {
+            if warnings[i] != msg {
+                t.Errorf("%s: expected warning %q, but got %q", test.input, msg, warnings[i])
+            }
+        }
+    }
+}
+
 func TestIdentifierExpression(t *testing.T) {
     input := "foobar;"
 
@@ -161,6 +371,102 @@ func TestIntegerLiteralExpression(t *testing.T) {
     }
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+    tests := []struct {
+        input string
+        expected int64
+    }{
+        {"0x1F;", 31},
+        {"0XfF;", 255},
+        {"0o17;", 15},
+        {"0b1010;", 10},
+        {"1_000_000;", 1000000},
+        {"0b1111_0000;", 240},
+        {"0;", 0},
+        {"0o777;", 511},
+        {"9223372036854775807;", 9223372036854775807},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        il, ok := stmt.Expression.(*ast.IntegerLiteral)
+        if !ok {
+            t.Fatalf("type assertion invalid")
+        }
+
+        if il.Value != test.expected {
+            t.Errorf("%s: %d expected, but got %d", test.input, test.expected, il.Value)
+        }
+    }
+}
+
+func TestBigIntegerLiteral(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"9223372036854775808;", "9223372036854775808"},
+        {"18446744073709551614;", "18446744073709551614"},
+        {"0x1_0000_0000_0000_0000;", "18446744073709551616"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        il, ok := stmt.Expression.(*ast.IntegerLiteral)
+        if !ok {
+            t.Fatalf("type assertion invalid")
+        }
+
+        if il.Big == nil || il.Big.String() != test.expected {
+            t.Errorf("%s: %s expected, but got %v", test.input, test.expected, il.Big)
+        }
+    }
+}
+
+func TestIntegerLiteralErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedMsg string
+    }{
+        {"0x;", `hexadecimal literal "0x" has no digits`},
+        {"0x1G;", `invalid digit 'G' in hexadecimal literal "0x1G"`},
+        {"0o18;", `invalid digit '8' in octal literal "0o18"`},
+        {"0b102;", `invalid digit '2' in binary literal "0b102"`},
+        {"1__000;", `'_' must separate successive digits in "1__000"`},
+        {"1_;", `'_' must separate successive digits in "1_"`},
+        {"0x_1;", `'_' must separate successive digits in "0x_1"`},
+        {"010;", `leading zeros are not allowed in decimal literal "010"; use 0o for octal`},
+        {"0777;", `leading zeros are not allowed in decimal literal "0777"; use 0o for octal`},
+        {"0_0;", `leading zeros are not allowed in decimal literal "0_0"; use 0o for octal`},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 {
+            t.Errorf("%s: expected parser error, but got none", test.input)
+            continue
+        }
+
+        if errors[0] != test.expectedMsg {
+            t.Errorf("wrong error message\n%q expected, but got %q", test.expectedMsg, errors[0])
+        }
+    }
+}
+
 func TestStringLiteralExpression(t *testing.T) {
     input := `"hello world";`
 
@@ -273,10 +579,16 @@ func TestParsingInfixExpressions(t *testing.T) {
         {"2 - 3;", 2, "-" , 3},
         {"2 * 3;", 2, "*" , 3},
         {"2 / 3;", 2, "/" , 3},
+        {"2 // 3;", 2, "//" , 3},
+        {"2 % 3;", 2, "%" , 3},
         {"2 < 3;", 2, "<" , 3},
         {"2 > 3;", 2, ">" , 3},
         {"2 == 3;", 2, "==" , 3},
         {"2 != 3;", 2, "!=" , 3},
+        {"2 <= 3;", 2, "<=" , 3},
+        {"2 >= 3;", 2, ">=" , 3},
+        {"true && false;", true, "&&", false},
+        {"true || false;", true, "||", false},
         {"true == true;", true, "==", true},
         {"false != true;", false, "!=", true},
         {"false == false;", false, "==", false},
@@ -472,6 +784,51 @@ func TestFunctionParameterParsing(t *testing.T) {
     }
 }
 
+func TestFunctionParameterKinds(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"fn(x, y = 10) { x + y }", "fn(x, y = 10)(x + y)"},
+        {"fn(first, ...others) { others }", "fn(first, ...others)others"},
+        {"fn(x = 1, y = x * 2, ...r) { r }", "fn(x = 1, y = (x * 2), ...r)r"},
+        {"f(1, y: 2, z: 3)", "f(1, y: 2, z: 3)"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if program.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, program.String())
+        }
+    }
+}
+
+func TestFunctionParameterErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedMsg string
+    }{
+        {"fn(x = 1, y) { y }", "parameter y without default follows parameter with default"},
+        {"fn(...r, x) { x }", "expected next token to be ), but got , instead"},
+        {"f(y: 1, 2)", "positional argument follows named argument"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) == 0 || errors[0] != test.expectedMsg {
+            t.Errorf("%s: expected error %q, but got %q", test.input, test.expectedMsg, errors)
+        }
+    }
+}
+
 func TestFunctionCallParsing(t *testing.T) {
     //input := "fn(x, y) { x + y; }(3, 6)"
     input := "add(1, 2 * 3, 4 + 5)"
@@ -567,14 +924,18 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
         "three": 3,
     }
 
-    for key, val := range hl.Pairs {
+    for _, pair := range hl.Pairs {
 
-        sl, ok := key.(*ast.StringLiteral)
+        sl, ok := pair.Key.(*ast.StringLiteral)
         if !ok {
             t.Errorf("type assertion error")
         }
 
-        testIntegerLiteral(t, val, expected[sl.Value])
+        testIntegerLiteral(t, pair.Value, expected[sl.Value])
+    }
+
+    if hl.String() != "{one:1, two:2, three:3}" {
+        t.Errorf("pairs must keep source order, got %s", hl.String())
     }
 }
 
@@ -628,15 +989,135 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
         },
     }
 
-    for key, val := range hl.Pairs {
-        sl, ok := key.(*ast.StringLiteral)
+    for _, pair := range hl.Pairs {
+        sl, ok := pair.Key.(*ast.StringLiteral)
         f, ok := tests[sl.Value]
         if !ok {
             t.Errorf("not found function")
             continue
         }
 
-        f(val)
+        f(pair.Value)
+    }
+}
+
+func TestParsingSetLiterals(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+        elems int
+    }{
+        {"{1}", "{1}", 1},
+        {"{1, 2 + 3, x}", "{1, (2 + 3), x}", 3},
+        {"{[1, 2], \"a\",}", "{[1, 2], a}", 2},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        stmt := program.Statements[0].(*ast.ExpressionStatement)
+        sl, ok := stmt.Expression.(*ast.SetLiteral)
+        if !ok {
+            t.Fatalf("%s: expected SetLiteral, but got %T", test.input, stmt.Expression)
+        }
+        if len(sl.Elems) != test.elems {
+            t.Errorf("%s: expected %d elems, but got %d", test.input, test.elems, len(sl.Elems))
+        }
+        if sl.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, sl.String())
+        }
+    }
+
+    p := New(lexer.New("{1, 2: 3}"))
+    p.ParseProgram()
+    if len(p.Errors()) == 0 || p.Errors()[0] != "expected next token to be }, but got : instead" {
+        t.Errorf("unexpected errors: %v", p.Errors())
+    }
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+    l := lexer.New("1 + 2 = 3;")
+    p := New(l)
+    p.ParseProgram()
+
+    errors := p.Errors()
+    if len(errors) == 0 || errors[0] != "cannot assign to (1 + 2)" {
+        t.Errorf("expected error %q, but got %q", "cannot assign to (1 + 2)", errors)
+    }
+}
+
+func TestLoopStatements(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {
+            "while (x < 10) { x; }",
+            "while(x < 10) x",
+        },
+        {
+            "for (x in xs) { puts(x); }",
+            "for(x in xs) puts(x)",
+        },
+        {
+            "while (true) { if (x) { break; } continue; }",
+            "whiletrue ifx break;continue;",
+        },
+        {
+            "while (x < 10) { x; };",
+            "while(x < 10) x",
+        },
+        {
+            "for (x in xs) { puts(x); };",
+            "for(x in xs) puts(x)",
+        },
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        program := p.ParseProgram()
+        checkParserErrors(t, p)
+
+        if len(program.Statements) != 1 {
+            t.Fatalf("program.Statements length expected 1, but got %d", len(program.Statements))
+        }
+
+        if program.String() != test.expected {
+            t.Errorf("expected %s, but got %s", test.expected, program.String())
+        }
+    }
+
+    p := New(lexer.New("while (i < 5) { i += 1 }; i; for (x in xs) { x }; i"))
+    program := p.ParseProgram()
+    checkParserErrors(t, p)
+    if len(program.Statements) != 4 {
+        t.Errorf("program.Statements length expected 4, but got %d", len(program.Statements))
+    }
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+    tests := []struct {
+        input string
+        expectedMsg string
+    }{
+        {"break;", "break outside of loop"},
+        {"if (true) { continue; }", "continue outside of loop"},
+        {"while (true) { fn() { break; } }", "break outside of loop"},
+    }
+
+    for _, test := range tests {
+        l := lexer.New(test.input)
+        p := New(l)
+        p.ParseProgram()
+
+        errors := p.Errors()
+        if len(errors) != 1 || errors[0] != test.expectedMsg {
+            t.Errorf("%s: expected error %q, but got %q", test.input, test.expectedMsg, errors)
+        }
     }
 }
 
@@ -770,6 +1251,10 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
             "a + b / c",
             "(a + (b / c))",
         },
+        {
+            "a - b % c // d",
+            "(a - ((b % c) // d))",
+        },
         {
             "5 > 4 == 3 < 4",
             "((5 > 4) == (3 < 4))",
@@ -794,6 +1279,86 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
             "3 < 5 != true",
             "((3 < 5) != true)",
         },
+        {
+            "a + 1 <= b == c >= d",
+            "(((a + 1) <= b) == (c >= d))",
+        },
+        {
+            "x = y = 1 + 2",
+            "(x = (y = (1 + 2)))",
+        },
+        {
+            "x += y || z",
+            "(x += (y || z))",
+        },
+        {
+            "a[i] -= f(x)",
+            "((a[i]) -= f(x))",
+        },
+        {
+            "a || b && c == d",
+            "(a || (b && (c == d)))",
+        },
+        {
+            "a && b || c && d",
+            "((a && b) || (c && d))",
+        },
+        {
+            "arr |> push(1) |> rest |> puts",
+            "puts(rest(push(arr, 1)))",
+        },
+        {
+            "a + 1 |> f(b * 2)",
+            "f((a + 1), (b * 2))",
+        },
+        {
+            "x = a ?? b |> f",
+            "(x = f((a ?? b)))",
+        },
+        {
+            "xs |> fn(v) { v }",
+            "fn(v)v(xs)",
+        },
+        {
+            "a ? b : c ? d : e",
+            "(a ? b : (c ? d : e))",
+        },
+        {
+            "a || b ? c + 1 : d ?? e",
+            "((a || b) ? (c + 1) : (d ?? e))",
+        },
+        {
+            "a ?? b || c",
+            "(a ?? (b || c))",
+        },
+        {
+            "x = a ? b : c",
+            "(x = (a ? b : c))",
+        },
+        {
+            "1..n + 1",
+            "(1 .. (n + 1))",
+        },
+        {
+            "0..<len(a) == r",
+            "((0 ..< len(a)) == r)",
+        },
+        {
+            "a[1:2] + a[:n - 1] + a[::-1] + a[i:]",
+            "((((a[1:2]) + (a[:(n - 1)])) + (a[::(-1)])) + (a[i:]))",
+        },
+        {
+            "a[c ? 1 : 2:]",
+            "(a[(c ? 1 : 2):])",
+        },
+        {
+            "a?.[1:]",
+            "(a?.[1:])",
+        },
+        {
+            "a?.[b]?.[c] ?? d",
+            "(((a?.[b])?.[c]) ?? d)",
+        },
         {
             "1 + (2 + 3) + 4",
             "((1 + (2 + 3)) + 4)",
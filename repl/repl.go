@@ -2,7 +2,6 @@ package repl
 
 import (
     "bufio"
-    "fmt"
     "io"
     "monkey_interpreter/lexer"
     "monkey_interpreter/parser"
@@ -12,15 +11,12 @@ import (
 
 const PROMPT = ">>> "
 
-
-// バグあり
-// 例えば、`let a 46`と入力した時、panic runtime errorが起こり、replが終了してしまう
 func Start(in io.Reader, out io.Writer) {
     scanner := bufio.NewScanner(in)
     env := object.NewEnv()
 
     for {
-        fmt.Printf(PROMPT)
+        io.WriteString(out, PROMPT)
         scanned := scanner.Scan()
         // scanが終わるとscannedはfalseになる
         if !scanned {
@@ -30,10 +26,14 @@ func Start(in io.Reader, out io.Writer) {
         line := scanner.Text()
         l := lexer.New(line)
         p := parser.New(l)
+        p.SetBuiltins(eval.BuiltinNames())
 
         program := p.ParseProgram()
+        printParserWarnings(out, p.Warnings())
+        // 構文エラーのあるASTは一部がnilになりうるので評価しない
         if len(p.Errors()) != 0 {
             printParserErrors(out, p.Errors())
+            continue
         }
 
         evaled := eval.Eval(program, env)
@@ -49,3 +49,9 @@ func printParserErrors(out io.Writer, errors []string) {
         io.WriteString(out, "\t" + msg + "\n")
     }
 }
+
+func printParserWarnings(out io.Writer, warnings []string) {
+    for _, msg := range warnings {
+        io.WriteString(out, "\twarning: " + msg + "\n")
+    }
+}
@@ -0,0 +1,43 @@
+package repl
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+)
+
+func TestStartWithParseErrors(t *testing.T) {
+    tests := []struct {
+        input string
+        expected string
+    }{
+        {"let a 46", "expected next token to be =, but got INT instead"},
+        {"let [a, b] =", "not found prefix parse function"},
+        {"x +=", "not found prefix parse function"},
+        {"1..", "not found prefix parse function"},
+        {"throw", "not found prefix parse function"},
+    }
+
+    for _, test := range tests {
+        var out bytes.Buffer
+        Start(strings.NewReader(test.input + "\n1 + 1\n"), &out)
+
+        if !strings.Contains(out.String(), test.expected) {
+            t.Errorf("%q: expected output to contain %q, but got %q", test.input, test.expected, out.String())
+        }
+        // 構文エラーの後も続けて入力を評価できる
+        if !strings.HasSuffix(out.String(), "2\n" + PROMPT) {
+            t.Errorf("%q: REPL did not continue after the error, got %q", test.input, out.String())
+        }
+    }
+}
+
+func TestStartKeepsEnv(t *testing.T) {
+    var out bytes.Buffer
+    Start(strings.NewReader("let x = 2\nlet y = \nx * 3\n"), &out)
+
+    expected := PROMPT + PROMPT + "\t"
+    if !strings.HasPrefix(out.String(), expected) || !strings.HasSuffix(out.String(), PROMPT + "6\n" + PROMPT) {
+        t.Errorf("unexpected output %q", out.String())
+    }
+}
@@ -5,6 +5,9 @@ type TokenType string
 type Token struct {
     Type TokenType
     Literal string
+    // ソース上の位置(1始まり). 診断メッセージに用いる
+    Line int
+    Column int
 }
 
 const (
@@ -20,15 +23,33 @@ const (
 
     // operator
     ASSGIN = "="
+    PLUS_ASSIGN = "+="
+    MINUS_ASSIGN = "-="
+    MUL_ASSIGN = "*="
+    DIV_ASSIGN = "/="
+    FLOORDIV_ASSIGN = "//="
+    MOD_ASSIGN = "%="
     PLUS = "+"
     MINUS = "-"
     MUL = "*"
     DIV = "/"
+    FLOORDIV = "//"
+    MOD = "%"
     LT = "<"
     GT = ">"
+    LE = "<="
+    GE = ">="
     BANG = "!"
+    QUESTION = "?"
+    NULLISH = "??"
+    OPTIONAL_CHAIN = "?."
+
+    RANGE = ".."
+    RANGE_EXCL = "..<"
 
     // delimiter
+    ARROW = "=>"
+    ELLIPSIS = "..."
     COMMA = ","
     COLON = ":"
     SEMICOLON = ";"
@@ -42,25 +63,50 @@ const (
 
     EQ = "=="
     NQ = "!="
+    AND = "&&"
+    OR = "||"
+    PIPE = "|>"
 
     // keyword
     FUNCTION = "FUNCTION"
     LET = "LET"
+    CONST = "CONST"
     TRUE = "TRUE"
     FALSE = "FALSE"
     IF = "IF"
     ELSE = "ELSE"
     RETURN = "RETURN"
+    WHILE = "WHILE"
+    FOR = "FOR"
+    IN = "IN"
+    BREAK = "BREAK"
+    CONTINUE = "CONTINUE"
+    MATCH = "MATCH"
+    TRY = "TRY"
+    CATCH = "CATCH"
+    FINALLY = "FINALLY"
+    THROW = "THROW"
 )
 
 var keywords = map[string]TokenType {
     "let": LET,
+    "const": CONST,
     "fn": FUNCTION,
     "true": TRUE,
     "false": FALSE,
     "if": IF,
     "else": ELSE,
     "return": RETURN,
+    "while": WHILE,
+    "for": FOR,
+    "in": IN,
+    "break": BREAK,
+    "continue": CONTINUE,
+    "match": MATCH,
+    "try": TRY,
+    "catch": CATCH,
+    "finally": FINALLY,
+    "throw": THROW,
 }
 
 func LookupIdent(str string) TokenType {